@@ -1,46 +1,172 @@
 package capTheorem
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
 )
 
 //AP (Availability + Partition Tolerance)
 //Availability and Partition Tolerance are guaranteed, but Consistency might be compromised during network partitions.
 //This means that even if some nodes can't communicate, the system will still accept reads and writes, but they may not reflect the latest data.
 //Example: AP System
-//In this example, we simulate a scenario where the system continues to function during a partition, but we might get stale data due to the sacrifice of consistency.
+//In this example, every node accepts writes as soon as it stored them itself (W=1) and ships them to its peers without waiting.
+//Each write carries a vector clock, so writes made on both sides of a partition are not lost: they become siblings.
+//A client reading from every replica after the partition sees the siblings and resolves them with a merge callback.
+//After the partition, Merkle-tree anti-entropy copies the missing versions between replicas, so every node ends up with both siblings.
+//With a CRDT as the value instead, replicas merge by themselves and converge as soon as the partition heals.
+
+const systemAP = "SimulateNetworkPartitionAP"
+
+// Resolver merges the values of sibling versions into one.
+type Resolver func(siblings []string) string
+
+func SimulateNetworkPartitionAP(ctx context.Context, cfg config.Network) error {
+	c := newCluster(cfg, "A", "B", "C")
+	c.StartAntiEntropy(500 * time.Millisecond)
+	if err := loadCatalog(ctx, c, "A", 100); err != nil {
+		return err
+	}
+	writeDataAP(ctx, c, "A", "milk", nil)
+	if err := c.Run(ctx, 100*time.Millisecond); err != nil {
+		return err
+	}
 
-func SimulateNetworkPartitionAP() {
-	// Simulate network partition
-	isPartition = true
+	// Simulate network partition: C is cut off from A and B
+	partition(ctx, systemAP, c, []string{"A", "B"}, []string{"C"})
 
-	// Write operation (allowed even during partition)
-	writeDataAP("SimulateNetworkPartitionAP: Data during Partition")
+	// Clients on both sides read the cart and add an item (allowed even during partition)
+	_, seenA := readDataAP(ctx, c, "A", 1)
+	writeDataAP(ctx, c, "A", "milk,eggs", seenA)
+	_, seenC := readDataAP(ctx, c, "C", 1)
+	writeDataAP(ctx, c, "C", "milk,bread", seenC)
+	updateCatalog(c, "A", 3, 7, 11)
+	updateCatalog(c, "C", 42, 57)
+	if err := c.Run(ctx, 100*time.Millisecond); err != nil {
+		return err
+	}
 
-	// Read operation (could return stale data)
-	fmt.Println("SimulateNetworkPartitionAP: Read during partition:", readDataCP())
+	// Read operations (return different values on each side)
+	readDataAP(ctx, c, "B", 1)
+	readDataAP(ctx, c, "C", 1)
+	showReplicas(ctx, systemAP, c, dataKey)
 
 	// Simulate removing the partition
-	time.Sleep(2 * time.Second)
-	isPartition = false
+	if err := c.Run(ctx, 2*time.Second); err != nil {
+		return err
+	}
+	heal(ctx, systemAP, c)
+	showReplicas(ctx, systemAP, c, dataKey)
 
-	// After partition removed, system operates normally
-	writeDataAP("SimulateNetworkPartitionAP: Data after Partition")
-	fmt.Println("SimulateNetworkPartitionAP: Read after partition:", readDataAP())
+	// Anti-entropy compares Merkle trees and only ships the keys of the ranges that differ
+	events.Emit(ctx, events.Result{Name: systemAP + " keys diverged", Value: c.DivergedKeys()})
+	before := c.AntiEntropy
+	if err := c.Run(ctx, 2*time.Second); err != nil {
+		return err
+	}
+	events.Emit(ctx, events.Result{Name: systemAP + " keys diverged", Value: c.DivergedKeys()})
+	events.Emit(ctx, events.Result{Name: systemAP + " anti-entropy", Value: c.AntiEntropy.since(before)})
+	showReplicas(ctx, systemAP, c, dataKey)
+
+	// Reading every replica finds both carts, the client merges them into one version
+	resolveAP(ctx, c, "B", unionResolver)
+	if err := c.Run(ctx, 100*time.Millisecond); err != nil {
+		return err
+	}
+	showReplicas(ctx, systemAP, c, dataKey)
+
+	if err := simulateCRDTCart(ctx, c); err != nil {
+		return err
+	}
+	showNetwork(ctx, systemAP, c)
+	_, err := checkHistory(ctx, systemAP, c)
+	return err
+}
+
+// writeDataAP stores newData once the local replica has it; seen is the clock of what the client read.
+func writeDataAP(ctx context.Context, c *Cluster, nodeID, newData string, seen VectorClock) error {
+	return writeVersionAP(ctx, c, nodeID, newData, seen, 1)
 }
 
-func writeDataAP(newData string) {
-	if isPartition {
-		// During partition, we still allow writes, but might lead to stale data
-		data = newData
-		fmt.Println("SimulateNetworkPartitionAP: Data written:", data)
-		return
+func writeVersionAP(ctx context.Context, c *Cluster, nodeID, newData string, seen VectorClock, w int) error {
+	err := c.await(func(done func(error)) { c.Node(nodeID).putQuorum(dataKey, newData, seen, w, done) })
+	if err != nil {
+		events.Emit(ctx, events.WriteRejected{System: systemAP, Node: nodeID, Value: newData, Reason: err.Error()})
+		return err
 	}
-	data = newData
-	fmt.Println("SimulateNetworkPartitionAP: Data written:", data)
+	events.Emit(ctx, events.WriteAccepted{System: systemAP, Node: nodeID, Value: newData})
+	return nil
 }
 
-func readDataAP() string {
-	return data // Data could be stale during partition
+// readDataAP returns the versions r replicas hold; with r=1 that is whatever the local node has.
+func readDataAP(ctx context.Context, c *Cluster, nodeID string, r int) ([]Version, VectorClock) {
+	var siblings []Version
+	err := c.await(func(done func(error)) {
+		c.Node(nodeID).getQuorum(dataKey, r, func(versions []Version, err error) {
+			siblings = versions
+			done(err)
+		})
+	})
+	if err != nil {
+		events.Emit(ctx, events.ReadRejected{System: systemAP, Node: nodeID, Reason: err.Error()})
+		return nil, nil
+	}
+	if len(siblings) > 1 {
+		events.Emit(ctx, events.SiblingsFound{System: systemAP, Node: nodeID, Key: dataKey, Siblings: siblingValues(siblings)})
+	} else {
+		events.Emit(ctx, events.ReadServed{System: systemAP, Node: nodeID, Value: strings.Join(siblingValues(siblings), "")})
+	}
+	return siblings, mergeContext(siblings) // Data could be stale during partition
+}
+
+// resolveAP reads key from every replica and, if it finds siblings, writes back their merge
+// with a clock that supersedes all of them.
+func resolveAP(ctx context.Context, c *Cluster, nodeID string, resolve Resolver) error {
+	siblings, seen := readDataAP(ctx, c, nodeID, c.ReplicationFactor)
+	if len(siblings) < 2 {
+		return nil
+	}
+	return writeVersionAP(ctx, c, nodeID, resolve(siblingValues(siblings)), seen, c.ReplicationFactor)
+}
+
+// loadCatalog writes n catalog keys on nodeID and lets them replicate.
+func loadCatalog(ctx context.Context, c *Cluster, nodeID string, n int) error {
+	for i := 0; i < n; i++ {
+		key := catalogKey(i)
+		if err := c.await(func(done func(error)) { c.Node(nodeID).putQuorum(key, "price 1", nil, 1, done) }); err != nil {
+			return err
+		}
+	}
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: %d catalog keys loaded", systemAP, n)})
+	return c.Run(ctx, 100*time.Millisecond)
+}
+
+// updateCatalog changes a few catalog keys on nodeID, acknowledged by the local replica only.
+func updateCatalog(c *Cluster, nodeID string, items ...int) {
+	for _, i := range items {
+		c.Node(nodeID).putQuorum(catalogKey(i), "price 2 from "+nodeID, nil, 1, func(error) {})
+	}
+}
+
+func catalogKey(i int) string { return fmt.Sprintf("item-%03d", i) }
+
+// unionResolver merges comma separated sets, like the items of shopping carts.
+func unionResolver(siblings []string) string {
+	seen := make(map[string]bool)
+	for _, s := range siblings {
+		for _, item := range strings.Split(s, ",") {
+			seen[item] = true
+		}
+	}
+	items := make([]string, 0, len(seen))
+	for item := range seen {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
 }
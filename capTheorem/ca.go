@@ -1,50 +1,100 @@
 package capTheorem
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
 )
 
 //CA (Consistency + Availability)
 //CA systems, Consistency and Availability are guaranteed, but Partition Tolerance is sacrificed.
 //This means that the system will always be available for reads and writes and will maintain consistency unless a network partition occurs, at which point the system becomes unavailable.
 //Example: CA System (Unavailable on Partition)
-//Here we simulate a CA system where, during a partition, the system becomes unavailable.
+//Here every write is replicated to all nodes, and as soon as a node suspects any peer it stops serving, even if it can still reach the others.
+//Nodes learn about the partition from their failure detectors: until they do, writes wait for the unreachable peer and time out.
+
+const systemCA = "SimulateNetworkPartitionCA"
+
+func SimulateNetworkPartitionCA(ctx context.Context, cfg config.Network, det config.Detector) error {
+	c := newCluster(cfg, "A", "B", "C")
+	if err := c.StartFailureDetector(det); err != nil {
+		return err
+	}
+	if err := c.Run(ctx, time.Second); err != nil {
+		return err
+	}
 
-func SimulateNetworkPartitionCA() {
 	// Normal operation: no partition
-	writeData("SimulateNetworkPartitionCA: Initial Data")
-	fmt.Println("SimulateNetworkPartitionCA: Read data:", readData())
+	writeData(ctx, c, "A", "Initial Data")
+	readData(ctx, c, "C")
 
 	// Simulate network partition (System becomes unavailable)
-	isPartition = true
-	writeData("SimulateNetworkPartitionCA: New Data during Partition") // Write fails
-	readData()                                                         // Read fails
+	partition(ctx, systemCA, c, []string{"A", "B"}, []string{"C"})
+	writeData(ctx, c, "A", "New Data during Partition") // Write times out: A does not suspect C yet
+	readData(ctx, c, "B")                               // Read fails: B suspects C by now
 
 	// Simulate removing the partition
-	time.Sleep(2 * time.Second)
-	isPartition = false
+	if err := c.Run(ctx, 2*time.Second); err != nil {
+		return err
+	}
+	heal(ctx, systemCA, c)
+	for _, id := range []string{"B", "C"} {
+		awaitSuspicion(ctx, systemCA, c, id, false)
+	}
 
 	// After partition removed, we can write and read again
-	writeData("SimulateNetworkPartitionCA: Data after Partition")
-	fmt.Println("SimulateNetworkPartitionCA: Read after partition:", readData())
+	writeData(ctx, c, "B", "Data after Partition")
+	readData(ctx, c, "C")
+	showReplicas(ctx, systemCA, c, dataKey)
+	showNetwork(ctx, systemCA, c)
+	_, err := checkHistory(ctx, systemCA, c)
+	return err
 }
 
-func writeData(newData string) {
-	if isPartition {
+// partitionedFrom reports whether nodeID has lost sight of any peer.
+func partitionedFrom(c *Cluster, nodeID string) bool {
+	for _, peer := range c.peers(nodeID) {
+		if c.Node(nodeID).suspects(peer.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeData(ctx context.Context, c *Cluster, nodeID, newData string) error {
+	if partitionedFrom(c, nodeID) {
 		// During partition, we cannot perform writes
-		fmt.Println("SimulateNetworkPartitionCA: Network partition detected. Cannot write data.")
-		return
+		events.Emit(ctx, events.WriteRejected{System: systemCA, Node: nodeID, Value: newData, Reason: "Network partition detected"})
+		return ErrUnavailable
 	}
-	data = newData
-	fmt.Println("SimulateNetworkPartitionCA : Data written:", data)
+	err := c.await(func(done func(error)) { c.Node(nodeID).writeSync(dataKey, newData, done) })
+	if errors.Is(err, ErrTimeout) {
+		// The node does not suspect anybody yet: it waited for the vote of an unreachable replica in vain
+		events.Emit(ctx, events.WriteRejected{System: systemCA, Node: nodeID, Value: newData, Reason: "Timed out waiting for every replica, no partition detected yet"})
+		return err
+	}
+	if err != nil {
+		events.Emit(ctx, events.WriteRejected{System: systemCA, Node: nodeID, Value: newData, Reason: err.Error()})
+		return err
+	}
+	events.Emit(ctx, events.WriteAccepted{System: systemCA, Node: nodeID, Value: newData})
+	return nil
 }
 
-func readData() string {
-	if isPartition {
+func readData(ctx context.Context, c *Cluster, nodeID string) (string, error) {
+	if partitionedFrom(c, nodeID) {
 		// During partition, we cannot read data
-		fmt.Println("SimulateNetworkPartitionCA: Network partition detected. Cannot read data.")
-		return ""
+		events.Emit(ctx, events.ReadRejected{System: systemCA, Node: nodeID, Reason: "Network partition detected"})
+		return "", ErrUnavailable
+	}
+	v, err := c.readSync(nodeID, dataKey)
+	if err != nil {
+		events.Emit(ctx, events.ReadRejected{System: systemCA, Node: nodeID, Reason: err.Error()})
+		return "", err
 	}
-	return data
+	events.Emit(ctx, events.ReadServed{System: systemCA, Node: nodeID, Value: v})
+	return v, nil
 }
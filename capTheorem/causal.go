@@ -0,0 +1,270 @@
+package capTheorem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"GoBestPratices/capTheorem/linearizability"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+//Causal consistency (COPS style)
+//In AP mode every key replicates on its own, so a replica may show a reply before the post it answers.
+//In causal mode a client keeps a context: the versions it read or wrote, one clock per key.
+//Each write carries that context as its dependencies, and a replica receiving the write delays applying it
+//until every dependency is visible locally. Since the dependencies were themselves applied after theirs,
+//a write only becomes visible once everything it causally depends on is.
+//After a write the client's context is only that write: it already depends on everything the client saw (nearest dependencies).
+//Writes are acknowledged by the local replica and sent to every other node until they acknowledge it,
+//so causal mode stays available during a partition; remote replicas just see the writes later.
+
+const systemCausal = "SimulateCausalConsistency"
+
+// Dependency is a version of a key a write causally depends on.
+type Dependency struct {
+	Key   string
+	Clock VectorClock
+}
+
+func (d Dependency) String() string { return d.Key + d.Clock.String() }
+
+// CausalStats counts the writes replicas had to delay for their dependencies.
+type CausalStats struct {
+	Replicated int           `json:"replicated"`
+	Delayed    int           `json:"delayed"`
+	MaxDelay   time.Duration `json:"max_delay"`
+}
+
+func (s CausalStats) String() string {
+	return fmt.Sprintf("replicated=%d delayed=%d max delay=%v", s.Replicated, s.Delayed, s.MaxDelay.Round(time.Millisecond))
+}
+
+type causalPut struct {
+	Req     uint64
+	Key     string
+	Version Version
+	Deps    []Dependency
+}
+
+type causalAck struct {
+	Req uint64
+}
+
+// delayedPut is a write a replica received before its dependencies.
+type delayedPut struct {
+	put     causalPut
+	arrived time.Time
+}
+
+// CausalClient reads and writes in causal mode, tracking the dependencies of its next write.
+type CausalClient struct {
+	c       *Cluster
+	context map[string]VectorClock
+}
+
+// NewCausalClient returns a client that has not seen anything yet.
+func (c *Cluster) NewCausalClient() *CausalClient {
+	return &CausalClient{c: c, context: make(map[string]VectorClock)}
+}
+
+// Get reads key from the replica on nodeID and adds what it saw to the client's context.
+func (cl *CausalClient) Get(nodeID, key string) []Version {
+	versions := cl.c.Node(nodeID).readLocal(key)
+	if len(versions) > 0 {
+		cl.context[key] = mergeContext(versions).Merge(cl.context[key])
+	}
+	return versions
+}
+
+// Put writes key on nodeID after everything in the client's context.
+func (cl *CausalClient) Put(nodeID, key, value string) {
+	v := cl.c.Node(nodeID).putCausal(key, value, cl.Dependencies())
+	cl.context = map[string]VectorClock{key: v.Clock}
+}
+
+// Dependencies returns the client's context, sorted by key.
+func (cl *CausalClient) Dependencies() []Dependency {
+	deps := make([]Dependency, 0, len(cl.context))
+	for key, clock := range cl.context {
+		deps = append(deps, Dependency{Key: key, Clock: clock})
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Key < deps[j].Key })
+	return deps
+}
+
+// putCausal applies a write locally and replicates it to every other node with its dependencies.
+func (n *Node) putCausal(key, value string, deps []Dependency) Version {
+	record := n.cluster.invoke(n.ID, linearizability.KVInput{Op: "put", Key: key, Value: value})
+	v := n.newVersion(key, value, mergeContext(n.store[key]))
+	n.apply(key, v)
+	record(value, nil)
+
+	n.seq++
+	put := causalPut{Req: n.seq, Key: key, Version: v, Deps: deps}
+	unacked := make(map[string]bool)
+	for _, peer := range n.cluster.peers(n.ID) {
+		unacked[peer.ID] = true
+	}
+	n.causalSends[put.Req] = unacked
+	n.sendCausal(put)
+	return v
+}
+
+// sendCausal sends a write to every node that has not acknowledged it yet, until they all did.
+func (n *Node) sendCausal(put causalPut) {
+	unacked, ok := n.causalSends[put.Req]
+	if !ok {
+		return
+	}
+	for _, peer := range n.cluster.peers(n.ID) {
+		if unacked[peer.ID] {
+			n.send(peer.ID, put)
+		}
+	}
+	n.cluster.net.After(n.cluster.Retry, func() { n.sendCausal(put) })
+}
+
+func (n *Node) onCausalAck(from string, a causalAck) {
+	unacked, ok := n.causalSends[a.Req]
+	if !ok {
+		return
+	}
+	delete(unacked, from)
+	if len(unacked) == 0 {
+		delete(n.causalSends, a.Req)
+	}
+}
+
+func (n *Node) onCausalPut(from string, p causalPut) {
+	n.send(from, causalAck{Req: p.Req})
+	if n.visible(p.Key, p.Version.Clock) {
+		// A retransmission of a write already applied
+		return
+	}
+	for _, d := range n.delayed {
+		if d.put.Key == p.Key && d.put.Version.Clock.Compare(p.Version.Clock) == Equal {
+			// A retransmission of a write still waiting for its dependencies
+			return
+		}
+	}
+	if !n.depsVisible(p.Deps) {
+		n.cluster.Causal.Delayed++
+	}
+	n.delayed = append(n.delayed, delayedPut{put: p, arrived: n.cluster.net.Now()})
+	n.applyCausal()
+}
+
+// applyCausal applies every delayed write whose dependencies are visible, until none is left that can be.
+func (n *Node) applyCausal() {
+	for progress := true; progress; {
+		progress = false
+		waiting := n.delayed[:0]
+		for _, d := range n.delayed {
+			if !n.depsVisible(d.put.Deps) {
+				waiting = append(waiting, d)
+				continue
+			}
+			if !n.visible(d.put.Key, d.put.Version.Clock) {
+				n.apply(d.put.Key, d.put.Version)
+				n.cluster.Causal.Replicated++
+				n.cluster.Causal.MaxDelay = max(n.cluster.Causal.MaxDelay, n.cluster.net.Now().Sub(d.arrived))
+			}
+			progress = true
+		}
+		n.delayed = waiting
+	}
+}
+
+// visible reports whether the node holds clock's version of key, or a later one.
+func (n *Node) visible(key string, clock VectorClock) bool {
+	return mergeContext(n.store[key]).Covers(clock)
+}
+
+func (n *Node) depsVisible(deps []Dependency) bool {
+	for _, d := range deps {
+		if !n.visible(d.Key, d.Clock) {
+			return false
+		}
+	}
+	return true
+}
+
+// SimulateCausalConsistency replies to a post while the post is slow to reach one node,
+// first with AP replication, then in causal mode.
+func SimulateCausalConsistency(ctx context.Context, cfg config.Network) error {
+	for _, causal := range []bool{false, true} {
+		if err := runPostAndReply(ctx, cfg, causal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	postKey  = "post"
+	replyKey = "reply"
+)
+
+// runPostAndReply has Alice post on A, Bob read it and reply on B, and Carol read both on C,
+// while the link from A to C is slow.
+func runPostAndReply(ctx context.Context, cfg config.Network, causal bool) error {
+	mode := "AP"
+	if causal {
+		mode = "causal"
+	}
+	system := systemCausal + " " + mode
+	c := newCluster(cfg, "A", "B", "C")
+	c.net.SetLink("A", "C", Link{Latency: Fixed(time.Second)})
+	events.Emit(ctx, events.Note{Text: system + ": messages from A to C take 1s"})
+
+	var read func(nodeID, key string) []Version
+	var write func(nodeID, key, value string)
+	if causal {
+		alice, bob, carol := c.NewCausalClient(), c.NewCausalClient(), c.NewCausalClient()
+		clients := map[string]*CausalClient{"A": alice, "B": bob, "C": carol}
+		read = func(nodeID, key string) []Version { return clients[nodeID].Get(nodeID, key) }
+		write = func(nodeID, key, value string) { clients[nodeID].Put(nodeID, key, value) }
+	} else {
+		read = func(nodeID, key string) []Version { return c.Node(nodeID).readLocal(key) }
+		write = func(nodeID, key, value string) {
+			c.await(func(done func(error)) { c.Node(nodeID).putQuorum(key, value, nil, 1, done) })
+		}
+	}
+	show := func(nodeID, key string) string {
+		value := strings.Join(siblingValues(read(nodeID, key)), " | ")
+		events.Emit(ctx, events.ReadServed{System: system, Node: nodeID, Value: key + "=" + value})
+		return value
+	}
+
+	write("A", postKey, "Lost my keys")
+	events.Emit(ctx, events.WriteAccepted{System: system, Node: "A", Value: postKey + "=Lost my keys"})
+	if err := c.Run(ctx, 100*time.Millisecond); err != nil {
+		return err
+	}
+	if show("B", postKey) != "" {
+		write("B", replyKey, "Found them")
+		events.Emit(ctx, events.WriteAccepted{System: system, Node: "B", Value: replyKey + "=Found them"})
+	}
+	if err := c.Run(ctx, 100*time.Millisecond); err != nil {
+		return err
+	}
+
+	// Carol reads the reply first: if she sees it, she should see the post too
+	reply := show("C", replyKey)
+	post := show("C", postKey)
+	events.Emit(ctx, events.Result{Name: system + " reply visible before the post", Value: reply != "" && post == ""})
+
+	if err := c.Run(ctx, 2*time.Second); err != nil {
+		return err
+	}
+	show("C", replyKey)
+	show("C", postKey)
+	if causal {
+		events.Emit(ctx, events.Result{Name: system + " replication", Value: c.Causal})
+	}
+	return nil
+}
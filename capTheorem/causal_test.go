@@ -0,0 +1,80 @@
+package capTheorem
+
+import (
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+)
+
+func TestCausalDelaysWritesUntilDependenciesAreVisible(t *testing.T) {
+	c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond}, "A", "B", "C")
+	c.Network().SetLink("A", "C", Link{Latency: Fixed(time.Second)})
+	alice, bob := c.NewCausalClient(), c.NewCausalClient()
+
+	alice.Put("A", postKey, "post")
+	c.Network().RunFor(100 * time.Millisecond)
+	if got := bob.Get("B", postKey); len(got) != 1 {
+		t.Fatalf("B holds %v; want the post", got)
+	}
+	bob.Put("B", replyKey, "reply")
+	if deps := bob.Dependencies(); len(deps) != 1 || deps[0].Key != replyKey {
+		t.Errorf("context after a write = %v; want only the write", deps)
+	}
+
+	c.Network().RunFor(100 * time.Millisecond)
+	if v, ok := c.Node("C").Get(replyKey); ok {
+		t.Errorf("C shows the reply %q before the post", v)
+	}
+	c.Network().RunFor(2 * time.Second)
+	for _, key := range []string{postKey, replyKey} {
+		if v, _ := c.Node("C").Get(key); v != key {
+			t.Errorf("C holds %s=%q; want %q", key, v, key)
+		}
+	}
+	if c.Causal.Delayed != 1 {
+		t.Errorf("Causal.Delayed = %d; want 1", c.Causal.Delayed)
+	}
+}
+
+func TestCausalAvailableDuringPartition(t *testing.T) {
+	c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond}, "A", "B", "C")
+	c.Network().Partition([]string{"A", "B"}, []string{"C"})
+	alice := c.NewCausalClient()
+	alice.Put("C", postKey, "post")
+	if v, _ := c.Node("C").Get(postKey); v != "post" {
+		t.Fatalf("write on the minority not applied locally: %q", v)
+	}
+	c.Network().RunFor(time.Second)
+	c.Network().Heal()
+	c.Network().RunFor(time.Second)
+	for _, n := range c.Nodes() {
+		if v, _ := n.Get(postKey); v != "post" {
+			t.Errorf("%s holds %q after the heal", n.ID, v)
+		}
+		if len(n.causalSends) != 0 {
+			t.Errorf("%s still waits for %d acknowledgements", n.ID, len(n.causalSends))
+		}
+	}
+}
+
+func TestCausalIgnoresDuplicates(t *testing.T) {
+	// Every message is delivered twice, and the reply reaches C long before the post it depends on
+	c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond, Duplicate: 1}, "A", "B", "C")
+	c.Network().SetLink("A", "C", Link{Latency: Fixed(time.Second)})
+	alice, bob := c.NewCausalClient(), c.NewCausalClient()
+
+	alice.Put("A", postKey, "post")
+	c.Network().RunFor(100 * time.Millisecond)
+	bob.Get("B", postKey)
+	bob.Put("B", replyKey, "reply")
+	c.Network().RunFor(500 * time.Millisecond)
+	if n := len(c.Node("C").delayed); n != 1 {
+		t.Errorf("C delays %d writes; want the reply once", n)
+	}
+
+	c.Network().RunFor(2 * time.Second)
+	if c.Causal.Delayed != 1 {
+		t.Errorf("Causal.Delayed = %d; want 1", c.Causal.Delayed)
+	}
+}
@@ -1,48 +1,108 @@
 package capTheorem
 
 import (
+	"context"
 	"fmt"
 	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
 )
 
 //CP (Consistency + Partition Tolerance)
 //In CP systems, Consistency and Partition Tolerance are guaranteed, but Availability is sacrificed.
 //If a partition occurs, the system might reject reads and writes to maintain consistency.
 //Example: CP System
-//In this example, we simulate a scenario where partition tolerance is maintained, but we are willing to sacrifice availability in favor of consistency.
+//In this example, the nodes run Raft or Multi-Paxos: every write and read is a command the leader orders in a replicated log,
+//and a command only completes once a majority of the nodes stored it.
+//During a partition, the side with a majority elects a leader and keeps serving, while the minority refuses every operation:
+//it can never gather a majority, so it can never return data the majority has since overwritten.
 
-func SimulateNetworkPartitionCP() {
-	// Simulate network partition
-	isPartition = true
+const systemCP = "SimulateNetworkPartitionCP"
 
-	// Write operation (will be rejected due to partition)
-	writeDataCP("SimulateNetworkPartitionCP: New Data")
+func SimulateNetworkPartitionCP(ctx context.Context, cfg config.Network, engine string) error {
+	c := newCluster(cfg, "A", "B", "C")
+	if err := c.StartConsensus(engine); err != nil {
+		return err
+	}
+	leader, err := awaitLeader(ctx, c, "")
+	if err != nil {
+		return err
+	}
+	writeDataCP(ctx, c, "A", "New Data")
 
-	// Read operation (will also be rejected due to partition)
-	readDataCP()
+	// Simulate network partition: the leader is cut off from the two other nodes
+	var majority []string
+	for _, n := range c.Nodes() {
+		if n.ID != leader {
+			majority = append(majority, n.ID)
+		}
+	}
+	partition(ctx, systemCP, c, majority, []string{leader})
+
+	// The old leader cannot reach a majority: writes and reads on its side are refused
+	writeDataCP(ctx, c, leader, "New Data on the minority")
+	readDataCP(ctx, c, leader)
+
+	// The majority elects a new leader and keeps serving
+	if _, err := awaitLeader(ctx, c, leader); err != nil {
+		return err
+	}
+	writeDataCP(ctx, c, majority[0], "New Data during Partition")
+	readDataCP(ctx, c, majority[1])
+	showReplicas(ctx, systemCP, c, dataKey)
 
 	// Simulate removing the partition
-	time.Sleep(2 * time.Second)
-	isPartition = false
+	if err := c.Run(ctx, 2*time.Second); err != nil {
+		return err
+	}
+	heal(ctx, systemCP, c)
 
-	// After partition removed, we can write and read again
-	writeDataCP("SimulateNetworkPartitionCP: New Data After Partition - ")
-	fmt.Println("SimulateNetworkPartitionCP: Read after partition:", readDataCP())
+	// The old leader sees the newer term, steps down and catches up with the log
+	writeDataCP(ctx, c, leader, "New Data After Partition")
+	readDataCP(ctx, c, majority[0])
+	if err := c.Run(ctx, 100*time.Millisecond); err != nil {
+		return err
+	}
+	showReplicas(ctx, systemCP, c, dataKey)
+	showNetwork(ctx, systemCP, c)
+	if _, err := checkHistory(ctx, systemCP, c); err != nil {
+		return err
+	}
+	events.Emit(ctx, events.Result{Name: systemCP + " " + engine + " commands", Value: c.Commands})
+	return nil
+}
+
+// awaitLeader runs the simulation until a node other than previous leads, and reports it.
+func awaitLeader(ctx context.Context, c *Cluster, previous string) (string, error) {
+	elected := func() bool {
+		id, _ := c.Leader()
+		return id != "" && id != previous
+	}
+	if !c.net.RunUntil(elected, 10*c.ElectionTimeout) {
+		return "", fmt.Errorf("%s: %w", systemCP, ErrNotLeader)
+	}
+	id, term := c.Leader()
+	events.Emit(ctx, events.LeaderElected{System: systemCP, Node: id, Term: term})
+	return id, ctx.Err()
 }
 
-func writeDataCP(newData string) {
-	if isPartition {
-		fmt.Println("SimulateNetworkPartitionCP: Network partition detected. Cannot write data.")
-		return
+func writeDataCP(ctx context.Context, c *Cluster, nodeID, newData string) error {
+	_, err := c.propose(nodeID, Command{Op: "put", Key: dataKey, Value: newData})
+	if err != nil {
+		events.Emit(ctx, events.WriteRejected{System: systemCP, Node: nodeID, Value: newData, Reason: err.Error()})
+		return err
 	}
-	data = newData
-	fmt.Println("SimulateNetworkPartitionCP: Data written:", data)
+	events.Emit(ctx, events.WriteAccepted{System: systemCP, Node: nodeID, Value: newData})
+	return nil
 }
 
-func readDataCP() string {
-	if isPartition {
-		fmt.Println("SimulateNetworkPartitionCP: Network partition detected. Cannot read data.")
-		return ""
+func readDataCP(ctx context.Context, c *Cluster, nodeID string) (string, error) {
+	v, err := c.propose(nodeID, Command{Op: "get", Key: dataKey})
+	if err != nil {
+		events.Emit(ctx, events.ReadRejected{System: systemCP, Node: nodeID, Reason: err.Error()})
+		return "", err
 	}
-	return data
+	events.Emit(ctx, events.ReadServed{System: systemCP, Node: nodeID, Value: v})
+	return v, nil
 }
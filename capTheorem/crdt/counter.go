@@ -0,0 +1,85 @@
+package crdt
+
+import "fmt"
+
+// GCounter is a counter that only grows. Each node increments its own entry
+// and a merge keeps the highest entry of every node.
+type GCounter struct {
+	Counts map[string]uint64 `json:"counts"`
+}
+
+// Increment adds n on behalf of node and returns the delta.
+func (c *GCounter) Increment(node string, n uint64) *GCounter {
+	if c.Counts == nil {
+		c.Counts = make(map[string]uint64)
+	}
+	c.Counts[node] += n
+	return &GCounter{Counts: map[string]uint64{node: c.Counts[node]}}
+}
+
+// Value returns the sum of every node's increments.
+func (c *GCounter) Value() uint64 {
+	var sum uint64
+	for _, n := range c.Counts {
+		sum += n
+	}
+	return sum
+}
+
+// Merge joins o into c.
+func (c *GCounter) Merge(o *GCounter) {
+	if c.Counts == nil {
+		c.Counts = make(map[string]uint64)
+	}
+	for node, n := range o.Counts {
+		c.Counts[node] = max(c.Counts[node], n)
+	}
+}
+
+func (c *GCounter) Join(o State) { c.Merge(o.(*GCounter)) }
+
+func (c *GCounter) Clone() State {
+	out := &GCounter{}
+	out.Merge(c)
+	return out
+}
+
+func (c *GCounter) String() string { return fmt.Sprint(c.Value()) }
+
+// PNCounter is a counter that can go up and down, made of one GCounter for
+// increments and one for decrements.
+type PNCounter struct {
+	Inc GCounter `json:"inc"`
+	Dec GCounter `json:"dec"`
+}
+
+// Increment adds n on behalf of node and returns the delta.
+func (c *PNCounter) Increment(node string, n uint64) *PNCounter {
+	return &PNCounter{Inc: *c.Inc.Increment(node, n)}
+}
+
+// Decrement subtracts n on behalf of node and returns the delta.
+func (c *PNCounter) Decrement(node string, n uint64) *PNCounter {
+	return &PNCounter{Dec: *c.Dec.Increment(node, n)}
+}
+
+// Value returns the increments minus the decrements.
+func (c *PNCounter) Value() int64 {
+	return int64(c.Inc.Value()) - int64(c.Dec.Value())
+}
+
+// Merge joins o into c.
+func (c *PNCounter) Merge(o *PNCounter) {
+	c.Inc.Merge(&o.Inc)
+	c.Dec.Merge(&o.Dec)
+}
+
+func (c *PNCounter) Join(o State) { c.Merge(o.(*PNCounter)) }
+
+func (c *PNCounter) Clone() State {
+	out := &PNCounter{}
+	out.Merge(c)
+	return out
+}
+
+func (c *PNCounter) String() string { return fmt.Sprint(c.Value()) }
@@ -0,0 +1,58 @@
+package crdt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//Conflict-free replicated data types
+//A CRDT is a state that replicas update independently and merge in any order, any number of times, always ending up equal.
+//Merging is a join: it is commutative, associative and idempotent, so lost, duplicated or reordered messages do no harm.
+//Every mutator returns a delta, a small state holding only the change, which replicas ship instead of the whole state.
+//Deltas are merged exactly like full states, and every type serializes to JSON.
+
+// State is implemented by every CRDT of this package, so replicas can ship and merge them without knowing their type.
+type State interface {
+	// Join merges other, which must have the same type, into the state.
+	Join(other State)
+	// Clone returns a copy that does not share memory with the state.
+	Clone() State
+}
+
+// VersionVector counts, for every node, the updates a state has seen from it.
+type VersionVector map[string]uint64
+
+// descends reports whether v has seen everything o has seen.
+func (v VersionVector) descends(o VersionVector) bool {
+	for node, n := range o {
+		if v[node] < n {
+			return false
+		}
+	}
+	return true
+}
+
+func (v VersionVector) merge(o VersionVector) VersionVector {
+	out := make(VersionVector, len(v))
+	for node, n := range v {
+		out[node] = n
+	}
+	for node, n := range o {
+		out[node] = max(out[node], n)
+	}
+	return out
+}
+
+func (v VersionVector) String() string {
+	nodes := make([]string, 0, len(v))
+	for node := range v {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = fmt.Sprintf("%s:%d", node, v[node])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
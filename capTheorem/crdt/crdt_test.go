@@ -0,0 +1,154 @@
+package crdt
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// converges merges the replicas into each other in two different orders and
+// checks every replica ends with the same value.
+func converges(t *testing.T, name string, replicas []State, value func(State) string) string {
+	t.Helper()
+	forward := make([]State, len(replicas))
+	backward := make([]State, len(replicas))
+	for i := range replicas {
+		forward[i] = replicas[i].Clone()
+		backward[i] = replicas[i].Clone()
+	}
+	for i := range replicas {
+		for j := range replicas {
+			forward[i].Join(replicas[j])
+			backward[i].Join(replicas[len(replicas)-1-j])
+			backward[i].Join(replicas[len(replicas)-1-j]) // merging twice changes nothing
+		}
+	}
+	want := value(forward[0])
+	for i := range replicas {
+		if got := value(forward[i]); got != want {
+			t.Errorf("%s: replica %d = %s; want %s", name, i, got, want)
+		}
+		if got := value(backward[i]); got != want {
+			t.Errorf("%s: replica %d merged in reverse = %s; want %s", name, i, got, want)
+		}
+	}
+	return want
+}
+
+func TestConvergence(t *testing.T) {
+	str := func(s State) string { return fmt.Sprint(s) }
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var g1, g2 GCounter
+	g1.Increment("A", 2)
+	g2.Increment("B", 3)
+	g2.Increment("A", 1)
+
+	var p1, p2 PNCounter
+	p1.Increment("A", 5)
+	p2.Decrement("B", 2)
+
+	var l1, l2 LWWRegister
+	l1.Set("A", "first", t0)
+	l2.Set("B", "second", t0.Add(time.Second))
+
+	var m1, m2 MVRegister
+	m1.Set("A", "left")
+	m2.Set("B", "right")
+
+	var s1, s2 ORSet
+	s1.Add("A", "milk")
+	s2.Merge(&s1)
+	s2.Remove("milk") // only the add B has seen
+	s1.Add("A", "milk")
+	s2.Add("B", "bread")
+
+	var o1, o2 ORMap[PNCounter, *PNCounter]
+	o1.Update("A", "apples", func(c *PNCounter) State { return c.Increment("A", 3) })
+	o2.Update("B", "apples", func(c *PNCounter) State { return c.Decrement("B", 1) })
+	o2.Update("B", "pears", func(c *PNCounter) State { return c.Increment("B", 1) })
+
+	tests := []struct {
+		name     string
+		replicas []State
+		want     string
+	}{
+		{"GCounter", []State{&g1, &g2}, "5"},
+		{"PNCounter", []State{&p1, &p2}, "3"},
+		{"LWWRegister", []State{&l1, &l2}, "second"},
+		{"MVRegister", []State{&m1, &m2}, "[left right]"},
+		{"ORSet add wins", []State{&s1, &s2}, "[bread milk]"},
+		{"ORMap", []State{&o1, &o2}, "{apples:2 pears:1}"},
+	}
+	for _, tt := range tests {
+		if got := converges(t, tt.name, tt.replicas, str); got != tt.want {
+			t.Errorf("%s converged to %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestORSetRemove(t *testing.T) {
+	var a, b ORSet
+	b.Merge(a.Add("A", "milk"))
+	b.Merge(a.Remove("milk"))
+	if a.Contains("milk") || b.Contains("milk") {
+		t.Errorf("milk still present after an observed remove: %v %v", a.Elements(), b.Elements())
+	}
+}
+
+func TestDeltas(t *testing.T) {
+	// Shipping only the deltas gives the same state as shipping everything
+	var full, fromDeltas ORMap[PNCounter, *PNCounter]
+	var deltas []State
+	deltas = append(deltas, full.Update("A", "x", func(c *PNCounter) State { return c.Increment("A", 2) }))
+	deltas = append(deltas, full.Update("A", "y", func(c *PNCounter) State { return c.Increment("A", 1) }))
+	deltas = append(deltas, full.Remove("y"))
+	deltas = append(deltas, full.Update("A", "x", func(c *PNCounter) State { return c.Decrement("A", 1) }))
+	for i := len(deltas) - 1; i >= 0; i-- {
+		fromDeltas.Join(deltas[i])
+	}
+	if fmt.Sprint(&fromDeltas) != fmt.Sprint(&full) {
+		t.Errorf("deltas merged in reverse = %v; want %v", &fromDeltas, &full)
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	var s ORSet
+	s.Add("A", "milk")
+	s.Add("B", "eggs")
+	s.Remove("milk")
+	var m ORMap[GCounter, *GCounter]
+	m.Update("A", "views", func(c *GCounter) State { return c.Increment("A", 4) })
+	var r MVRegister
+	r.Set("A", "v1")
+
+	tests := []struct {
+		name string
+		in   State
+		out  State
+	}{
+		{"ORSet", &s, &ORSet{}},
+		{"ORMap", &m, &ORMap[GCounter, *GCounter]{}},
+		{"MVRegister", &r, &MVRegister{}},
+		{"PNCounter", &PNCounter{}, &PNCounter{}},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.in)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := json.Unmarshal(data, tt.out); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(fmt.Sprint(tt.out), fmt.Sprint(tt.in)) {
+			t.Errorf("%s: round trip = %v; want %v (json %s)", tt.name, tt.out, tt.in, data)
+		}
+		// The decoded state keeps merging correctly
+		tt.out.Join(tt.in)
+		if fmt.Sprint(tt.out) != fmt.Sprint(tt.in) {
+			t.Errorf("%s: merge after round trip = %v; want %v", tt.name, tt.out, tt.in)
+		}
+	}
+}
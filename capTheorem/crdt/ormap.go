@@ -0,0 +1,86 @@
+package crdt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ORMap maps keys to nested CRDTs. Its keys behave like an ORSet, and the value
+// of a key is merged with the value of the same key on other replicas.
+// Removing a key hides it but keeps its value, so a concurrent update is not lost.
+// V is a pointer to T, for example ORMap[PNCounter, *PNCounter].
+type ORMap[T any, V interface {
+	*T
+	State
+}] struct {
+	Keys   ORSet        `json:"keys"`
+	Values map[string]V `json:"values"`
+}
+
+// Update applies mutate to the value of key on behalf of node and returns the delta.
+// mutate receives the current value, created if needed, and returns its delta.
+func (m *ORMap[T, V]) Update(node, key string, mutate func(V) State) *ORMap[T, V] {
+	if m.Values == nil {
+		m.Values = make(map[string]V)
+	}
+	v, ok := m.Values[key]
+	if !ok {
+		v = V(new(T))
+		m.Values[key] = v
+	}
+	delta := &ORMap[T, V]{Values: map[string]V{key: mutate(v).(V)}}
+	delta.Keys = *m.Keys.Add(node, key)
+	return delta
+}
+
+// Remove hides key as far as this replica has seen it and returns the delta.
+func (m *ORMap[T, V]) Remove(key string) *ORMap[T, V] {
+	return &ORMap[T, V]{Keys: *m.Keys.Remove(key)}
+}
+
+// Get returns the value of key if the key is in the map.
+func (m *ORMap[T, V]) Get(key string) (V, bool) {
+	if !m.Keys.Contains(key) {
+		return nil, false
+	}
+	return m.Values[key], true
+}
+
+// KeyList returns the keys in the map, sorted.
+func (m *ORMap[T, V]) KeyList() []string {
+	return m.Keys.Elements()
+}
+
+// Merge joins o into m.
+func (m *ORMap[T, V]) Merge(o *ORMap[T, V]) {
+	m.Keys.Merge(&o.Keys)
+	if m.Values == nil {
+		m.Values = make(map[string]V)
+	}
+	for key, ov := range o.Values {
+		if v, ok := m.Values[key]; ok {
+			v.Join(ov)
+		} else {
+			m.Values[key] = ov.Clone().(V)
+		}
+	}
+}
+
+func (m *ORMap[T, V]) Join(o State) { m.Merge(o.(*ORMap[T, V])) }
+
+func (m *ORMap[T, V]) Clone() State {
+	out := &ORMap[T, V]{}
+	out.Merge(m)
+	return out
+}
+
+func (m *ORMap[T, V]) String() string {
+	keys := m.KeyList()
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s:%v", key, m.Values[key])
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
@@ -0,0 +1,176 @@
+package crdt
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Dot identifies one add: the node that made it and that node's add count.
+type Dot struct {
+	Node    string `json:"node"`
+	Counter uint64 `json:"counter"`
+}
+
+// ORSet is an observed-remove set: a remove only deletes the adds it has seen,
+// so an element added concurrently with its removal stays in the set (add wins).
+// Every add is tagged with a fresh dot, and the set remembers every dot it has seen
+// (its causal context) to tell a removed add from one it never heard of.
+type ORSet struct {
+	entries map[string]map[Dot]bool
+	context map[Dot]bool
+}
+
+func (s *ORSet) init() {
+	if s.entries == nil {
+		s.entries = make(map[string]map[Dot]bool)
+	}
+	if s.context == nil {
+		s.context = make(map[Dot]bool)
+	}
+}
+
+// nextDot returns a dot node has never used.
+func (s *ORSet) nextDot(node string) Dot {
+	var last uint64
+	for d := range s.context {
+		if d.Node == node {
+			last = max(last, d.Counter)
+		}
+	}
+	return Dot{Node: node, Counter: last + 1}
+}
+
+// Add inserts elem on behalf of node and returns the delta.
+// The new add also replaces the adds of elem this replica has seen.
+func (s *ORSet) Add(node, elem string) *ORSet {
+	s.init()
+	d := s.nextDot(node)
+	delta := &ORSet{}
+	delta.init()
+	for old := range s.entries[elem] {
+		delta.context[old] = true
+	}
+	delta.entries[elem] = map[Dot]bool{d: true}
+	delta.context[d] = true
+	s.Merge(delta)
+	return delta
+}
+
+// Remove deletes elem as far as this replica has seen it and returns the delta.
+func (s *ORSet) Remove(elem string) *ORSet {
+	s.init()
+	delta := &ORSet{}
+	delta.init()
+	for d := range s.entries[elem] {
+		delta.context[d] = true
+	}
+	s.Merge(delta)
+	return delta
+}
+
+// Contains reports whether elem is in the set.
+func (s *ORSet) Contains(elem string) bool {
+	return len(s.entries[elem]) > 0
+}
+
+// Elements returns the members of the set, sorted.
+func (s *ORSet) Elements() []string {
+	out := make([]string, 0, len(s.entries))
+	for elem := range s.entries {
+		out = append(out, elem)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Merge joins o into s: an add survives if both sides have it,
+// or if the side missing it has never seen it.
+func (s *ORSet) Merge(o *ORSet) {
+	s.init()
+	elems := make(map[string]bool)
+	for elem := range s.entries {
+		elems[elem] = true
+	}
+	for elem := range o.entries {
+		elems[elem] = true
+	}
+	for elem := range elems {
+		kept := make(map[Dot]bool)
+		for d := range s.entries[elem] {
+			if o.entries[elem][d] || !o.context[d] {
+				kept[d] = true
+			}
+		}
+		for d := range o.entries[elem] {
+			if !s.context[d] {
+				kept[d] = true
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.entries, elem)
+		} else {
+			s.entries[elem] = kept
+		}
+	}
+	for d := range o.context {
+		s.context[d] = true
+	}
+}
+
+func (s *ORSet) Join(o State) { s.Merge(o.(*ORSet)) }
+
+func (s *ORSet) Clone() State {
+	out := &ORSet{}
+	out.Merge(s)
+	return out
+}
+
+func (s *ORSet) String() string { return fmt.Sprint(s.Elements()) }
+
+// orSetJSON is the serialized form of an ORSet, with dots sorted for stable output.
+type orSetJSON struct {
+	Entries map[string][]Dot `json:"entries"`
+	Context []Dot            `json:"context"`
+}
+
+func sortedDots(dots map[Dot]bool) []Dot {
+	out := make([]Dot, 0, len(dots))
+	for d := range dots {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Node != out[j].Node {
+			return out[i].Node < out[j].Node
+		}
+		return out[i].Counter < out[j].Counter
+	})
+	return out
+}
+
+func (s *ORSet) MarshalJSON() ([]byte, error) {
+	v := orSetJSON{Entries: make(map[string][]Dot), Context: sortedDots(s.context)}
+	for elem, dots := range s.entries {
+		v.Entries[elem] = sortedDots(dots)
+	}
+	return json.Marshal(v)
+}
+
+func (s *ORSet) UnmarshalJSON(data []byte) error {
+	var v orSetJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = ORSet{}
+	s.init()
+	for elem, dots := range v.Entries {
+		s.entries[elem] = make(map[Dot]bool)
+		for _, d := range dots {
+			s.entries[elem][d] = true
+		}
+	}
+	for _, d := range v.Context {
+		s.context[d] = true
+	}
+	return nil
+}
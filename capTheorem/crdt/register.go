@@ -0,0 +1,106 @@
+package crdt
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LWWRegister holds a single value; concurrent writes are settled by the latest
+// timestamp, then by node id, so one of them is silently lost.
+type LWWRegister struct {
+	Value string    `json:"value"`
+	Time  time.Time `json:"time"`
+	Node  string    `json:"node"`
+}
+
+// Set writes value at the given time on behalf of node and returns the delta.
+func (r *LWWRegister) Set(node, value string, at time.Time) *LWWRegister {
+	r.Merge(&LWWRegister{Value: value, Time: at, Node: node})
+	return &LWWRegister{Value: r.Value, Time: r.Time, Node: r.Node}
+}
+
+// Merge keeps the latest of r and o.
+func (r *LWWRegister) Merge(o *LWWRegister) {
+	if o.Time.After(r.Time) || (o.Time.Equal(r.Time) && o.Node > r.Node) {
+		*r = *o
+	}
+}
+
+func (r *LWWRegister) Join(o State) { r.Merge(o.(*LWWRegister)) }
+
+func (r *LWWRegister) Clone() State {
+	out := *r
+	return &out
+}
+
+func (r *LWWRegister) String() string { return r.Value }
+
+// MVRegister keeps every value written concurrently, so the application decides
+// how to combine them, like the siblings of a vector-clocked key.
+type MVRegister struct {
+	Entries []MVEntry `json:"entries"`
+}
+
+// MVEntry is one value of an MVRegister with the version vector of its write.
+type MVEntry struct {
+	Value string        `json:"value"`
+	Clock VersionVector `json:"clock"`
+}
+
+// Set replaces every value node has seen with value and returns the delta.
+func (r *MVRegister) Set(node, value string) *MVRegister {
+	clock := VersionVector{}
+	for _, e := range r.Entries {
+		clock = clock.merge(e.Clock)
+	}
+	clock[node]++
+	r.Entries = []MVEntry{{Value: value, Clock: clock}}
+	return r.Clone().(*MVRegister)
+}
+
+// Values returns the concurrent values, sorted.
+func (r *MVRegister) Values() []string {
+	out := make([]string, len(r.Entries))
+	for i, e := range r.Entries {
+		out[i] = e.Value
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Merge keeps every entry of r and o that no other entry has seen.
+func (r *MVRegister) Merge(o *MVRegister) {
+	all := append(append([]MVEntry{}, r.Entries...), o.Entries...)
+	var out []MVEntry
+	for i, e := range all {
+		superseded := false
+		for j, other := range all {
+			if i == j || !other.Clock.descends(e.Clock) {
+				continue
+			}
+			// Of two equal clocks keep the first one only
+			if !e.Clock.descends(other.Clock) || j < i {
+				superseded = true
+				break
+			}
+		}
+		if !superseded {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Clock.String() < out[j].Clock.String() })
+	r.Entries = out
+}
+
+func (r *MVRegister) Join(o State) { r.Merge(o.(*MVRegister)) }
+
+func (r *MVRegister) Clone() State {
+	out := &MVRegister{Entries: make([]MVEntry, len(r.Entries))}
+	for i, e := range r.Entries {
+		out.Entries[i] = MVEntry{Value: e.Value, Clock: e.Clock.merge(nil)}
+	}
+	return out
+}
+
+func (r *MVRegister) String() string { return fmt.Sprint(r.Values()) }
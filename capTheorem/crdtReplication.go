@@ -0,0 +1,167 @@
+package capTheorem
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"GoBestPratices/capTheorem/crdt"
+	"GoBestPratices/events"
+)
+
+//CRDT replication
+//Keys holding a CRDT are updated locally and replicated by shipping deltas instead of whole values.
+//Every node keeps, per peer, the join of the deltas that peer has not acknowledged yet and gossips it periodically.
+//A partition only delays the deltas: they pile up in the outbox and are delivered once the peer is reachable again,
+//and since merging is idempotent and order-insensitive, duplicated or reordered batches do no harm.
+
+// deltaBatch carries the unacknowledged deltas of every CRDT key to a peer.
+type deltaBatch struct {
+	Seq    uint64
+	Deltas map[string]crdt.State
+}
+
+type deltaAck struct {
+	Seq uint64
+}
+
+// updateCRDT applies mutate to the CRDT stored under key, created with empty if needed,
+// and queues the delta it returns for every peer.
+func updateCRDT[T crdt.State](n *Node, key string, empty func() T, mutate func(T) crdt.State) {
+	s, ok := n.crdts[key]
+	if !ok {
+		s = empty()
+		n.crdts[key] = s
+	}
+	delta := mutate(s.(T))
+	for _, peer := range n.cluster.peers(n.ID) {
+		if n.outbox[peer.ID] == nil {
+			n.outbox[peer.ID] = make(map[string]crdt.State)
+		}
+		if pending, ok := n.outbox[peer.ID][key]; ok {
+			pending.Join(delta)
+		} else {
+			n.outbox[peer.ID][key] = delta.Clone()
+		}
+		n.outboxSeq[peer.ID]++
+	}
+	n.startGossip()
+}
+
+// CRDT returns the state of the CRDT stored under key.
+func (n *Node) CRDT(key string) (crdt.State, bool) {
+	s, ok := n.crdts[key]
+	return s, ok
+}
+
+func (n *Node) startGossip() {
+	if n.gossiping {
+		return
+	}
+	n.gossiping = true
+	var tick func()
+	tick = func() {
+		n.gossip()
+		n.cluster.net.After(n.cluster.GossipInterval, tick)
+	}
+	n.cluster.net.After(n.cluster.GossipInterval, tick)
+}
+
+// gossip sends every peer the deltas it has not acknowledged yet.
+func (n *Node) gossip() {
+	for _, peer := range n.cluster.peers(n.ID) {
+		pending := n.outbox[peer.ID]
+		if len(pending) == 0 {
+			continue
+		}
+		batch := deltaBatch{Seq: n.outboxSeq[peer.ID], Deltas: make(map[string]crdt.State, len(pending))}
+		for key, d := range pending {
+			batch.Deltas[key] = d.Clone()
+		}
+		n.send(peer.ID, batch)
+	}
+}
+
+func (n *Node) onDeltaBatch(from string, b deltaBatch) {
+	for key, d := range b.Deltas {
+		if s, ok := n.crdts[key]; ok {
+			s.Join(d)
+		} else {
+			n.crdts[key] = d.Clone()
+		}
+	}
+	n.send(from, deltaAck{Seq: b.Seq})
+}
+
+func (n *Node) onDeltaAck(from string, a deltaAck) {
+	// Deltas queued after the batch was sent stay until a later batch is acknowledged
+	if a.Seq == n.outboxSeq[from] {
+		delete(n.outbox, from)
+	}
+}
+
+// CRDTReplicas returns the state every node holds for the CRDT key.
+func (c *Cluster) CRDTReplicas(key string) map[string]string {
+	out := make(map[string]string, len(c.nodes))
+	for _, n := range c.nodes {
+		if s, ok := n.CRDT(key); ok {
+			out[n.ID] = fmt.Sprint(s)
+		} else {
+			out[n.ID] = ""
+		}
+	}
+	return out
+}
+
+// Cart is a shopping cart CRDT: item -> quantity.
+type Cart = crdt.ORMap[crdt.PNCounter, *crdt.PNCounter]
+
+const cartKey = "cart"
+
+// updateCart changes the quantity of item on nodeID, or removes the item when change is 0.
+func updateCart(ctx context.Context, c *Cluster, nodeID, item string, change int) {
+	n := c.Node(nodeID)
+	empty := func() *Cart { return &Cart{} }
+	value := fmt.Sprintf("%s %+d", item, change)
+	switch {
+	case change > 0:
+		updateCRDT(n, cartKey, empty, func(cart *Cart) crdt.State {
+			return cart.Update(n.ID, item, func(q *crdt.PNCounter) crdt.State { return q.Increment(n.ID, uint64(change)) })
+		})
+	case change < 0:
+		updateCRDT(n, cartKey, empty, func(cart *Cart) crdt.State {
+			return cart.Update(n.ID, item, func(q *crdt.PNCounter) crdt.State { return q.Decrement(n.ID, uint64(-change)) })
+		})
+	default:
+		value = "remove " + item
+		updateCRDT(n, cartKey, empty, func(cart *Cart) crdt.State { return cart.Remove(item) })
+	}
+	events.Emit(ctx, events.WriteAccepted{System: systemAP, Node: nodeID, Value: value})
+}
+
+// simulateCRDTCart replays the AP partition with the cart as a CRDT: no siblings, no client merge.
+func simulateCRDTCart(ctx context.Context, c *Cluster) error {
+	events.Emit(ctx, events.Note{Text: systemAP + ": the same cart as a CRDT (OR-Map of PN-Counters)"})
+	updateCart(ctx, c, "A", "milk", 1)
+	if err := c.Run(ctx, 2*c.GossipInterval); err != nil {
+		return err
+	}
+
+	partition(ctx, systemAP, c, []string{"A", "B"}, []string{"C"})
+	updateCart(ctx, c, "A", "eggs", 2)
+	updateCart(ctx, c, "A", "milk", 0)
+	updateCart(ctx, c, "C", "bread", 1)
+	updateCart(ctx, c, "C", "eggs", 1)
+	if err := c.Run(ctx, time.Second); err != nil {
+		return err
+	}
+	events.Emit(ctx, events.ReplicaState{System: systemAP, Key: cartKey, Values: c.CRDTReplicas(cartKey)})
+
+	heal(ctx, systemAP, c)
+	// Buffered deltas reach the other side on the next gossip rounds
+	if err := c.Run(ctx, 3*c.GossipInterval); err != nil {
+		return err
+	}
+	events.Emit(ctx, events.ReplicaState{System: systemAP, Key: cartKey, Values: c.CRDTReplicas(cartKey)})
+	return nil
+}
@@ -0,0 +1,36 @@
+package capTheorem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+func TestCRDTConvergesAfterHeal(t *testing.T) {
+	ctx := events.WithSink(context.Background(), events.Discard)
+	for seed := 1; seed <= 5; seed++ {
+		c := newCluster(config.Network{Seed: seed, Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond,
+			Drop: 0.2, Duplicate: 0.2, Reorder: 0.2}, "A", "B", "C")
+
+		c.Network().Partition([]string{"A", "B"}, []string{"C"})
+		updateCart(ctx, c, "A", "eggs", 2)
+		updateCart(ctx, c, "B", "milk", 1)
+		updateCart(ctx, c, "C", "bread", 1)
+		updateCart(ctx, c, "C", "eggs", -1)
+		c.Network().RunFor(time.Second)
+		if c.CRDTReplicas(cartKey)["A"] == c.CRDTReplicas(cartKey)["C"] {
+			t.Fatalf("seed %d: replicas converged across a partition", seed)
+		}
+
+		c.Network().Heal()
+		c.Network().RunFor(2 * time.Second)
+		for node, v := range c.CRDTReplicas(cartKey) {
+			if v != "{bread:1 eggs:1 milk:1}" {
+				t.Errorf("seed %d: replica %s = %s after heal", seed, node, v)
+			}
+		}
+	}
+}
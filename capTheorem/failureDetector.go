@@ -0,0 +1,208 @@
+package capTheorem
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+//Failure detection
+//A node cannot see a partition: it only sees that a peer's messages stopped arriving, which a slow peer or a slow link looks like too.
+//Every node sends a heartbeat to every peer at a fixed interval, and a phi-accrual detector (Hayashibara et al.) keeps
+//a window of the gaps between the heartbeats of each peer. Assuming the gaps are normally distributed,
+//phi = -log10(probability that the next heartbeat arrives even later than now), so phi=8 means a 1 in 10^8 chance of being wrong.
+//A node suspects a peer once phi reaches the threshold: a low threshold detects crashes sooner but suspects slow nodes too,
+//a high one waits longer before giving up on anybody.
+//The detector learns: after the links became slow, the gaps it expects grow and the suspicions stop.
+
+const systemDetector = "SimulateFailureDetector"
+
+type heartbeat struct{}
+
+// phiAccrual estimates from the arrival times of one peer's heartbeats how likely it is that the peer failed.
+type phiAccrual struct {
+	cfg  config.Detector
+	last time.Time
+	// gaps holds the most recent intervals between heartbeats, at most cfg.Window
+	gaps []time.Duration
+}
+
+// newPhiAccrual returns a detector that expects heartbeats every cfg.Heartbeat, give or take a quarter, from now on.
+func newPhiAccrual(cfg config.Detector, now time.Time) *phiAccrual {
+	return &phiAccrual{cfg: cfg, last: now, gaps: []time.Duration{cfg.Heartbeat * 3 / 4, cfg.Heartbeat * 5 / 4}}
+}
+
+func (d *phiAccrual) heartbeat(now time.Time) {
+	if now.Before(d.last) {
+		// The clock of the node went back: start counting again
+		d.last = now
+		return
+	}
+	d.gaps = append(d.gaps, now.Sub(d.last))
+	if len(d.gaps) > d.cfg.Window {
+		d.gaps = d.gaps[len(d.gaps)-d.cfg.Window:]
+	}
+	d.last = now
+}
+
+// phi returns the suspicion level at now: 1 means a 10% chance that the peer is alive, 2 a 1% chance, and so on.
+func (d *phiAccrual) phi(now time.Time) float64 {
+	var sum float64
+	for _, g := range d.gaps {
+		sum += float64(g)
+	}
+	mean := sum / float64(len(d.gaps))
+	var variance float64
+	for _, g := range d.gaps {
+		variance += (float64(g) - mean) * (float64(g) - mean)
+	}
+	stdDev := max(math.Sqrt(variance/float64(len(d.gaps))), float64(d.cfg.MinStdDev))
+	mean += float64(d.cfg.AcceptablePause)
+
+	// Logistic approximation of the normal distribution's tail, as in Akka
+	elapsed := float64(now.Sub(d.last))
+	y := (elapsed - mean) / stdDev
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if elapsed > mean {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
+
+// StartFailureDetector makes every node send heartbeats to its peers and suspect them from the gaps between theirs.
+// Every simulation whose nodes act on suspicions starts it: until then, nodes suspect nobody.
+func (c *Cluster) StartFailureDetector(cfg config.Detector) error {
+	if cfg.Threshold <= 0 || cfg.Heartbeat <= 0 || cfg.MinStdDev <= 0 || cfg.AcceptablePause < 0 || cfg.Window < 2 {
+		return fmt.Errorf("invalid failure detector: threshold, heartbeat and min stddev must be positive, window at least 2, got %+v", cfg)
+	}
+	c.Detector = cfg
+	for i, n := range c.nodes {
+		n.detectors = make(map[string]*phiAccrual)
+		for _, peer := range c.peers(n.ID) {
+			n.detectors[peer.ID] = newPhiAccrual(cfg, n.now())
+		}
+		var beat func()
+		beat = func() {
+			n.broadcast(heartbeat{})
+			c.net.After(cfg.Heartbeat, beat)
+		}
+		// Spread the nodes' heartbeats over the interval
+		c.net.After(cfg.Heartbeat*time.Duration(i+1)/time.Duration(len(c.nodes)), beat)
+	}
+	return nil
+}
+
+func (n *Node) onHeartbeat(from string) {
+	if d, ok := n.detectors[from]; ok {
+		d.heartbeat(n.now())
+	}
+}
+
+// Phi returns how strongly the node suspects peer, 0 without a failure detector.
+func (n *Node) Phi(peer string) float64 {
+	d, ok := n.detectors[peer]
+	if !ok {
+		return 0
+	}
+	return d.phi(n.now())
+}
+
+// suspects reports whether the node's failure detector believes peer is unreachable.
+// Without a failure detector the node suspects nobody, unless the cluster is a test's with a PartitionOracle.
+func (n *Node) suspects(peer string) bool {
+	if n.detectors == nil {
+		return n.cluster.PartitionOracle && !n.cluster.net.Reachable(n.ID, peer)
+	}
+	return n.Phi(peer) >= n.cluster.Detector.Threshold
+}
+
+// awaitSuspicion runs the simulation until nodeID suspects a peer, or stops suspecting every peer,
+// and reports how long it took.
+func awaitSuspicion(ctx context.Context, system string, c *Cluster, nodeID string, suspect bool) time.Duration {
+	start := c.net.Elapsed()
+	c.net.RunUntil(func() bool { return partitionedFrom(c, nodeID) == suspect }, 10*time.Second)
+	took := (c.net.Elapsed() - start).Round(time.Millisecond)
+	what := "suspects a peer"
+	if !suspect {
+		what = "trusts every peer"
+	}
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: %s %s after %v", system, nodeID, what, took)})
+	return took
+}
+
+// SimulateFailureDetector shows what a node's phi-accrual detector makes of a peer that becomes slow, then of one that crashes.
+func SimulateFailureDetector(ctx context.Context, cfg config.Network, det config.Detector) error {
+	c := newCluster(cfg, "A", "B", "C")
+	if err := c.StartFailureDetector(det); err != nil {
+		return err
+	}
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: heartbeats every %v, suspect at phi >= %v", systemDetector, det.Heartbeat, det.Threshold)})
+	if err := c.Run(ctx, time.Second); err != nil {
+		return err
+	}
+	if err := watchPhi(ctx, c, "A", "C", "healthy", time.Second); err != nil {
+		return err
+	}
+
+	// C is alive, but its links become slow and erratic: the first long gap looks like a failure
+	slow := Uniform{Min: 50 * time.Millisecond, Max: 250 * time.Millisecond}
+	for _, peer := range c.peers("C") {
+		c.net.SetLink("C", peer.ID, Link{Latency: slow})
+		c.net.SetLink(peer.ID, "C", Link{Latency: slow})
+	}
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: the links of C now take %v to %v", systemDetector, slow.Min, slow.Max)})
+	if err := watchPhi(ctx, c, "A", "C", "slow", 3*time.Second); err != nil {
+		return err
+	}
+	for _, peer := range c.peers("C") {
+		c.net.ResetLink("C", peer.ID)
+		c.net.ResetLink(peer.ID, "C")
+	}
+	if err := c.Run(ctx, time.Second); err != nil {
+		return err
+	}
+
+	// C crashes: phi grows without bound, and how soon it crosses the threshold depends on what the detector learnt
+	c.Crash("C")
+	events.Emit(ctx, events.Note{Text: systemDetector + ": C crashed"})
+	if err := watchPhi(ctx, c, "A", "C", "crashed", time.Second); err != nil {
+		return err
+	}
+	showNetwork(ctx, systemDetector, c)
+	return nil
+}
+
+// watchPhi samples the phi nodeID computes for peer during d and reports how long peer was suspected.
+func watchPhi(ctx context.Context, c *Cluster, nodeID, peer, label string, d time.Duration) error {
+	n := c.Node(nodeID)
+	const sample = 10 * time.Millisecond
+	var suspected, first time.Duration
+	maxPhi := 0.0
+	detected := false
+	for t := time.Duration(0); t < d; t += sample {
+		if err := c.Run(ctx, sample); err != nil {
+			return err
+		}
+		phi := n.Phi(peer)
+		maxPhi = max(maxPhi, phi)
+		if n.suspects(peer) {
+			suspected += sample
+			if !detected {
+				detected, first = true, t+sample
+			}
+		}
+		if (t+sample)%(d/5) == 0 {
+			events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s %s: phi(%s->%s)=%.2f", systemDetector, label, nodeID, peer, phi)})
+		}
+	}
+	summary := fmt.Sprintf("suspected %v of %v, max phi=%.2f", suspected, d, maxPhi)
+	if detected {
+		summary += fmt.Sprintf(", first after %v", first)
+	}
+	events.Emit(ctx, events.Result{Name: fmt.Sprintf("%s %s %s", systemDetector, label, peer), Value: summary})
+	return nil
+}
@@ -0,0 +1,82 @@
+package capTheorem
+
+import (
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+)
+
+func TestPhiAccrual(t *testing.T) {
+	cfg := config.Default().Detector
+	start := time.Unix(0, 0)
+	d := newPhiAccrual(cfg, start)
+	now := start
+	for i := 0; i < 20; i++ {
+		now = now.Add(cfg.Heartbeat)
+		d.heartbeat(now)
+	}
+
+	prev := -1.0
+	for _, after := range []time.Duration{0, 50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond} {
+		phi := d.phi(now.Add(after))
+		if phi < prev {
+			t.Errorf("phi %v after the last heartbeat = %.2f; want at least %.2f", after, phi, prev)
+		}
+		prev = phi
+	}
+	if phi := d.phi(now.Add(cfg.Heartbeat)); phi >= cfg.Threshold {
+		t.Errorf("phi one interval after the last heartbeat = %.2f; want below the threshold", phi)
+	}
+	if phi := d.phi(now.Add(4 * cfg.Heartbeat)); phi < cfg.Threshold {
+		t.Errorf("phi four intervals after the last heartbeat = %.2f; want above the threshold", phi)
+	}
+
+	// Once the gaps vary, the same silence is less suspicious
+	for i := 0; i < 20; i++ {
+		now = now.Add(cfg.Heartbeat * time.Duration(1+i%4))
+		d.heartbeat(now)
+	}
+	if phi := d.phi(now.Add(4 * cfg.Heartbeat)); phi >= cfg.Threshold {
+		t.Errorf("phi four intervals after the last of irregular heartbeats = %.2f; want below the threshold", phi)
+	}
+}
+
+func TestFailureDetector(t *testing.T) {
+	c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond, Jitter: 2 * time.Millisecond}, "A", "B", "C")
+	if err := c.StartFailureDetector(config.Default().Detector); err != nil {
+		t.Fatal(err)
+	}
+	c.Network().RunFor(time.Second)
+	a := c.Node("A")
+
+	c.Network().Partition([]string{"A", "B"}, []string{"C"})
+	if a.suspects("C") {
+		t.Error("A suspects C as soon as the partition starts")
+	}
+	if !c.Network().RunUntil(func() bool { return a.suspects("C") }, time.Second) {
+		t.Fatalf("A does not suspect C, phi=%.2f", a.Phi("C"))
+	}
+	if a.suspects("B") {
+		t.Error("A suspects B, on its side of the partition")
+	}
+
+	c.Network().Heal()
+	if !c.Network().RunUntil(func() bool { return !a.suspects("C") }, time.Second) {
+		t.Errorf("A still suspects C after the heal, phi=%.2f", a.Phi("C"))
+	}
+
+	c.Crash("B")
+	if !c.Network().RunUntil(func() bool { return a.suspects("B") }, time.Second) {
+		t.Errorf("A does not suspect crashed B, phi=%.2f", a.Phi("B"))
+	}
+}
+
+func TestFailureDetectorRejectsInvalidConfig(t *testing.T) {
+	c := newCluster(config.Network{Seed: 1}, "A", "B")
+	cfg := config.Default().Detector
+	cfg.Heartbeat = 0
+	if err := c.StartFailureDetector(cfg); err == nil {
+		t.Error("StartFailureDetector accepted a zero heartbeat")
+	}
+}
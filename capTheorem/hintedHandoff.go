@@ -0,0 +1,258 @@
+package capTheorem
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+//Hinted handoff and read repair
+//Both narrow the window during which replicas of a key disagree, long before anti-entropy gets to it.
+//Hinted handoff: a write for a replica the coordinator cannot reach goes to the next reachable node on the ring instead,
+//which acknowledges it on the replica's behalf (a sloppy quorum) and keeps it as a hint until the replica is back.
+//Read repair: a read that finds a replica holding stale versions sends it the versions it is missing.
+
+const systemHandoff = "SimulateHintedHandoff"
+
+// HandoffStats counts the repairs done by hinted handoff and read repair.
+type HandoffStats struct {
+	HintsStored    int `json:"hints_stored"`
+	HintsDelivered int `json:"hints_delivered"`
+	ReadRepairs    int `json:"read_repairs"`
+}
+
+func (s HandoffStats) String() string {
+	return fmt.Sprintf("hints stored=%d hints delivered=%d read repairs=%d", s.HintsStored, s.HintsDelivered, s.ReadRepairs)
+}
+
+// hint is a write kept for a replica that was unreachable.
+type hint struct {
+	ID      uint64
+	Key     string
+	Version Version
+}
+
+// hintedPut asks a fallback node to keep a write for the replica For.
+type hintedPut struct {
+	Req     uint64
+	For     string
+	Key     string
+	Version Version
+}
+
+// hintReplay delivers the hints kept for a replica that is reachable again.
+type hintReplay struct {
+	Hints []hint
+}
+
+type hintAck struct {
+	IDs []uint64
+}
+
+// readRepair sends a stale replica the versions a read found on the others.
+type readRepair struct {
+	Key      string
+	Versions []Version
+}
+
+// fallbacks returns the nodes after the preference list of key on the ring that the node can reach.
+func (n *Node) fallbacks(key string) []string {
+	ring := n.cluster.PreferenceList(key, len(n.cluster.nodes))
+	var out []string
+	for _, id := range ring[min(n.cluster.ReplicationFactor, len(ring)):] {
+		if !n.suspects(id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// handOff sends the write for the unreachable replica to the first fallback and returns the unused ones.
+// Without a fallback the coordinator keeps the hint itself, but it does not count towards W.
+func (n *Node) handOff(put quorumPut, replica string, fallbacks []string) []string {
+	if len(fallbacks) == 0 {
+		n.storeHint(replica, put.Key, put.Version)
+		return nil
+	}
+	n.send(fallbacks[0], hintedPut{Req: put.Req, For: replica, Key: put.Key, Version: put.Version})
+	return fallbacks[1:]
+}
+
+func (n *Node) onHintedPut(from string, p hintedPut) {
+	n.storeHint(p.For, p.Key, p.Version)
+	n.send(from, quorumReply{Req: p.Req, For: p.For})
+}
+
+// storeHint keeps v for replica, once even if the put is retried, and starts replaying hints.
+func (n *Node) storeHint(replica, key string, v Version) {
+	for _, h := range n.hints[replica] {
+		if h.Key == key && h.Version.Clock.Compare(v.Clock) == Equal {
+			return
+		}
+	}
+	n.seq++
+	n.hints[replica] = append(n.hints[replica], hint{ID: n.seq, Key: key, Version: v})
+	n.cluster.Handoff.HintsStored++
+	n.startHandoff()
+}
+
+func (n *Node) startHandoff() {
+	if n.handingOff {
+		return
+	}
+	n.handingOff = true
+	var tick func()
+	tick = func() {
+		n.replayHints()
+		n.cluster.net.After(n.cluster.HintInterval, tick)
+	}
+	n.cluster.net.After(n.cluster.HintInterval, tick)
+}
+
+// replayHints sends every replica the node can reach again the hints kept for it.
+func (n *Node) replayHints() {
+	replicas := make([]string, 0, len(n.hints))
+	for id := range n.hints {
+		replicas = append(replicas, id)
+	}
+	sort.Strings(replicas)
+	for _, id := range replicas {
+		if len(n.hints[id]) > 0 && !n.suspects(id) {
+			n.send(id, hintReplay{Hints: slices.Clone(n.hints[id])})
+		}
+	}
+}
+
+func (n *Node) onHintReplay(from string, r hintReplay) {
+	ids := make([]uint64, len(r.Hints))
+	for i, h := range r.Hints {
+		n.apply(h.Key, h.Version)
+		ids[i] = h.ID
+	}
+	n.send(from, hintAck{IDs: ids})
+}
+
+func (n *Node) onHintAck(from string, a hintAck) {
+	kept := n.hints[from][:0]
+	for _, h := range n.hints[from] {
+		if slices.Contains(a.IDs, h.ID) {
+			n.cluster.Handoff.HintsDelivered++
+		} else {
+			kept = append(kept, h)
+		}
+	}
+	if len(kept) == 0 {
+		delete(n.hints, from)
+		return
+	}
+	n.hints[from] = kept
+}
+
+// Hints returns how many hints the node keeps for other replicas.
+func (n *Node) Hints() int {
+	count := 0
+	for _, hints := range n.hints {
+		count += len(hints)
+	}
+	return count
+}
+
+// readRepair sends the versions a read merged to every replica that answered with less.
+func (n *Node) readRepair(key string, op *quorumOp) {
+	for _, id := range op.replicas {
+		seen, ok := op.seen[id]
+		if !ok || sameVersions(seen, op.versions) {
+			continue
+		}
+		n.cluster.Handoff.ReadRepairs++
+		n.send(id, readRepair{Key: key, Versions: op.versions})
+	}
+}
+
+func sameVersions(a, b []Version) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Clock.Compare(b[i].Clock) != Equal {
+			return false
+		}
+	}
+	return true
+}
+
+// SimulateHintedHandoff isolates one replica of a key and shows how hints and read repair bring it up to date.
+func SimulateHintedHandoff(ctx context.Context, cfg config.Network, det config.Detector) error {
+	c := newCluster(cfg, "A", "B", "C", "D", "E")
+	c.ReplicationFactor = 3
+	c.HintedHandoff, c.ReadRepair = true, true
+	if err := c.StartFailureDetector(det); err != nil {
+		return err
+	}
+	prefs := c.PreferenceList(dataKey, c.ReplicationFactor)
+	coordinator, second, lone := prefs[0], prefs[1], prefs[2]
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: %s is stored on %v (N=%d)", systemHandoff, dataKey, prefs, c.ReplicationFactor)})
+	writeQuorum(ctx, systemHandoff, c, coordinator, "v1", 2)
+
+	var rest []string
+	for _, n := range c.Nodes() {
+		if n.ID != lone {
+			rest = append(rest, n.ID)
+		}
+	}
+	partition(ctx, systemHandoff, c, rest, []string{lone})
+
+	// W=3 is still met: once the coordinator suspects the replica, a fallback node acknowledges for it and keeps a hint
+	writeQuorum(ctx, systemHandoff, c, coordinator, "v2", 3)
+	for _, n := range c.Nodes() {
+		if n.Hints() > 0 {
+			events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: %s keeps %d hint(s) for %s", systemHandoff, n.ID, n.Hints(), lone)})
+		}
+	}
+	showReplicas(ctx, systemHandoff, c, dataKey)
+	if err := c.Run(ctx, time.Second); err != nil {
+		return err
+	}
+
+	heal(ctx, systemHandoff, c)
+	start := c.net.Elapsed()
+	c.net.RunUntil(func() bool { return !divergedOn(c, dataKey, prefs) }, 2*time.Second)
+	events.Emit(ctx, events.Result{Name: systemHandoff + " replicas in sync after heal", Value: (c.net.Elapsed() - start).Round(time.Millisecond)})
+	showReplicas(ctx, systemHandoff, c, dataKey)
+
+	// A lost message is not a partition: nobody suspects the replica, so no hint is kept
+	c.net.SetLink(coordinator, lone, Link{Drop: 1})
+	writeQuorum(ctx, systemHandoff, c, coordinator, "v3", 2)
+	if err := c.Run(ctx, time.Second); err != nil {
+		return err
+	}
+	c.net.ResetLink(coordinator, lone)
+	showReplicas(ctx, systemHandoff, c, dataKey)
+
+	// Reading every replica finds the stale one and repairs it
+	readQuorum(ctx, systemHandoff, c, second, c.ReplicationFactor)
+	if err := c.Run(ctx, 100*time.Millisecond); err != nil {
+		return err
+	}
+	showReplicas(ctx, systemHandoff, c, dataKey)
+	events.Emit(ctx, events.Result{Name: systemHandoff + " repairs", Value: c.Handoff})
+	showNetwork(ctx, systemHandoff, c)
+	_, err := checkHistory(ctx, systemHandoff, c)
+	return err
+}
+
+// divergedOn reports whether the replicas ids disagree on the value of key.
+func divergedOn(c *Cluster, key string, ids []string) bool {
+	first, _ := c.Node(ids[0]).Get(key)
+	for _, id := range ids[1:] {
+		if v, _ := c.Node(id).Get(key); v != first {
+			return true
+		}
+	}
+	return false
+}
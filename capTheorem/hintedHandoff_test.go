@@ -0,0 +1,76 @@
+package capTheorem
+
+import (
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+)
+
+func TestHintedHandoff(t *testing.T) {
+	c := newCluster(config.Network{Seed: 2, Latency: 10 * time.Millisecond}, "A", "B", "C", "D", "E")
+	c.ReplicationFactor = 3
+	c.HintedHandoff = true
+	c.PartitionOracle = true // the coordinator knows the replica is cut off without waiting for a detector
+	prefs := c.PreferenceList(dataKey, 3)
+	coordinator, lone := prefs[0], prefs[2]
+	var rest []string
+	for _, n := range c.Nodes() {
+		if n.ID != lone {
+			rest = append(rest, n.ID)
+		}
+	}
+	c.Network().Partition(rest, []string{lone})
+
+	if err := c.await(func(done func(error)) { c.Node(coordinator).putQuorum(dataKey, "v1", nil, 3, done) }); err != nil {
+		t.Fatalf("W=3 with a fallback node: %v", err)
+	}
+	if v, _ := c.Node(lone).Get(dataKey); v != "" {
+		t.Fatalf("isolated replica holds %q before the heal", v)
+	}
+
+	c.Network().Heal()
+	c.Network().RunFor(500 * time.Millisecond)
+	if v, _ := c.Node(lone).Get(dataKey); v != "v1" {
+		t.Errorf("replica after the heal holds %q; want the hinted write", v)
+	}
+	for _, n := range c.Nodes() {
+		if n.Hints() != 0 {
+			t.Errorf("%s still keeps %d hints", n.ID, n.Hints())
+		}
+	}
+	if got := c.Handoff; got.HintsStored != 1 || got.HintsDelivered != 1 {
+		t.Errorf("Handoff = %v; want one hint stored and delivered", got)
+	}
+}
+
+func TestReadRepair(t *testing.T) {
+	for _, repair := range []bool{false, true} {
+		c := newCluster(config.Network{Seed: 2, Latency: 10 * time.Millisecond}, "A", "B", "C")
+		c.ReadRepair = repair
+		c.Network().SetLink("A", "C", Link{Drop: 1})
+		if err := c.await(func(done func(error)) { c.Node("A").putQuorum(dataKey, "v1", nil, 2, done) }); err != nil {
+			t.Fatal(err)
+		}
+		c.Network().ResetLink("A", "C")
+
+		var got []Version
+		err := c.await(func(done func(error)) {
+			c.Node("B").getQuorum(dataKey, 3, func(versions []Version, err error) {
+				got = versions
+				done(err)
+			})
+		})
+		if err != nil || len(got) != 1 || got[0].Value != "v1" {
+			t.Fatalf("R=3 read = %v, %v; want v1", got, err)
+		}
+		c.Network().RunFor(100 * time.Millisecond)
+		want := ""
+		if repair {
+			want = "v1"
+		}
+		if v, _ := c.Node("C").Get(dataKey); v != want {
+			t.Errorf("ReadRepair=%v: stale replica holds %q; want %q", repair, v, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package capTheorem
+
+import (
+	"context"
+	"errors"
+
+	"GoBestPratices/capTheorem/linearizability"
+	"GoBestPratices/events"
+)
+
+//Operation histories
+//Every client read and write the simulations run is recorded with the virtual times of its call and its return,
+//so a run can be judged by the linearizability checker instead of by reading the printed messages.
+//Operations are recorded when they are called, without a return until they complete.
+//A write still in flight, or one that failed, may take effect on some replicas at any later time: it stays without a return,
+//unless the protocol knows it did not, as when two-phase commit aborted it everywhere.
+//A read that failed or is still in flight has no effect and observed nothing, so it is left out.
+
+// History returns the client operations recorded so far.
+func (c *Cluster) History() []linearizability.KVOperation {
+	history := make([]linearizability.KVOperation, 0, len(c.history))
+	for _, op := range c.history {
+		if op == nil || (op.Input.Op == "get" && op.Return.IsZero()) {
+			continue
+		}
+		history = append(history, *op)
+	}
+	return history
+}
+
+// notApplied wraps the failure of an operation that definitely did not take effect.
+type notApplied struct{ error }
+
+func (e notApplied) Unwrap() error { return e.error }
+
+// invoke records the call of an operation by client and returns the function that records its completion
+// with the value read, if it was a get. Failures wrapped in notApplied are dropped from the history.
+func (c *Cluster) invoke(client string, in linearizability.KVInput) func(output string, err error) {
+	op := &linearizability.KVOperation{Client: client, Input: in, Call: c.net.Now()}
+	i := len(c.history)
+	c.history = append(c.history, op)
+	return func(output string, err error) {
+		if err != nil {
+			// A failed read has no effect, and neither has a write known not to be applied;
+			// any other failed write may have one, at any later time
+			var definite notApplied
+			if in.Op == "get" || errors.As(err, &definite) {
+				c.history[i] = nil
+			}
+			return
+		}
+		if in.Op == "get" {
+			op.Output = output
+		}
+		op.Return = c.net.Now()
+	}
+}
+
+// checkHistory emits whether the operations of the run are linearizable, with a counterexample if not.
+// It gives up, with ctx's error, if ctx is cancelled during the check.
+func checkHistory(ctx context.Context, system string, c *Cluster) (linearizability.Result[linearizability.KVInput, string], error) {
+	r, err := linearizability.Check(ctx, linearizability.KVModel(), c.History())
+	if err != nil {
+		return r, err
+	}
+	events.Emit(ctx, events.Result{Name: system + " history", Value: r})
+	return r, nil
+}
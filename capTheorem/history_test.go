@@ -0,0 +1,91 @@
+package capTheorem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+)
+
+func TestHistory(t *testing.T) {
+	tests := []struct {
+		name string
+		r, w int
+		want bool
+	}{
+		{"R+W>N", 2, 2, true},
+		{"R=W=1 reads the minority", 1, 1, false},
+	}
+
+	for _, tt := range tests {
+		c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond}, "A", "B", "C")
+		c.ReplicationFactor = 3
+		c.Network().Partition([]string{"A", "B"}, []string{"C"})
+
+		if err := c.await(func(done func(error)) { c.Node("A").putQuorum(dataKey, "v1", nil, tt.w, done) }); err != nil {
+			t.Fatalf("%s: put: %v", tt.name, err)
+		}
+		c.Network().RunFor(50 * time.Millisecond)
+		reader := "C"
+		if tt.r > 1 {
+			reader = "B" // a read quorum is only reachable in the majority
+		}
+		c.await(func(done func(error)) {
+			c.Node(reader).getQuorum(dataKey, tt.r, func(_ []Version, err error) { done(err) })
+		})
+
+		history := c.History()
+		if len(history) != 2 || !history[0].Return.Before(history[1].Call) {
+			t.Fatalf("%s: history = %v; want the put returned before the get", tt.name, history)
+		}
+		got, err := checkHistory(context.Background(), "test", c)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.Linearizable != tt.want {
+			t.Errorf("%s: %v; want linearizable=%v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryFailedWrites(t *testing.T) {
+	c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond}, "A", "B", "C")
+	c.ReplicationFactor = 3
+	c.Network().Partition([]string{"A", "B"}, []string{"C"})
+
+	// Two-phase commit aborts the write everywhere: it never happened
+	if err := c.await(func(done func(error)) { c.Node("A").writeSync(dataKey, "aborted", done) }); err == nil {
+		t.Fatal("synchronous write succeeded during the partition")
+	}
+	// A quorum write that timed out may have been stored by some replicas
+	if err := c.await(func(done func(error)) { c.Node("A").putQuorum(dataKey, "maybe", nil, 3, done) }); err == nil {
+		t.Fatal("W=3 succeeded during the partition")
+	}
+
+	history := c.History()
+	if len(history) != 1 || history[0].Input.Value != "maybe" || !history[0].Return.IsZero() {
+		t.Errorf("history = %v; want only the timed out write, without a return", history)
+	}
+}
+
+func TestHistoryPendingWrites(t *testing.T) {
+	c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond}, "A", "B", "C")
+	c.ReplicationFactor = 3
+
+	// The write is in flight until every replica acknowledged it, but may already be visible
+	var result error = ErrTimeout
+	c.Node("A").writeSync(dataKey, "v1", func(err error) { result = err })
+	history := c.History()
+	if len(history) != 1 || !history[0].Return.IsZero() {
+		t.Fatalf("history = %v; want the write in flight, without a return", history)
+	}
+
+	c.Network().RunFor(time.Second)
+	if result != nil {
+		t.Fatalf("write: %v", result)
+	}
+	if history := c.History(); len(history) != 1 || history[0].Return.IsZero() {
+		t.Errorf("history = %v; want the write returned", history)
+	}
+}
@@ -0,0 +1,294 @@
+package capTheorem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"GoBestPratices/capTheorem/linearizability"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+//Randomized testing
+//In the style of Jepsen, concurrent clients run random reads and writes against a cluster while a nemesis injects faults,
+//then checkers judge what the clients observed rather than what the nodes claim:
+//- linearizable: the history of every client operation is checked against a key/value register per key
+//- converged: once the faults are repaired and the cluster settled, the replicas of every key hold the same value
+//Quorum replication does not promise linearizability, even with R+W>N: for it that check only reports what happened.
+//Every random choice, of the clients, the nemesis and the network, comes from one seed:
+//a failing run is replayed exactly from its seed, and can be kept as a regression test.
+
+const systemJepsen = "Jepsen"
+
+// ErrCheckFailed is returned when a randomized run breaks a property one of its checkers verifies.
+var ErrCheckFailed = errors.New("jepsen check failed")
+
+// Workload describes the system under test and the clients of a randomized run.
+type Workload struct {
+	// System is raft, paxos, quorum or ca
+	System string
+	// R and W are the read and write quorums of the quorum system, out of N=3
+	R, W    int
+	Nodes   int
+	Clients int
+	Keys    int
+	// Duration is how long clients run operations; the faults are then repaired and the cluster left to settle
+	Duration time.Duration
+	// Faults are the kinds of faults the nemesis picks from, see Faults
+	Faults []string
+	// FaultInterval is how long each fault, and each quiet period between two faults, lasts
+	FaultInterval time.Duration
+	// Detector configures the failure detector the ca nodes use to notice partitions
+	Detector config.Detector
+}
+
+// DefaultWorkload runs five clients on five nodes for ten seconds, with every kind of fault.
+func DefaultWorkload(system string) Workload {
+	return Workload{
+		System:        system,
+		R:             2,
+		W:             2,
+		Nodes:         5,
+		Clients:       5,
+		Keys:          3,
+		Duration:      10 * time.Second,
+		Faults:        Faults,
+		FaultInterval: time.Second,
+		Detector:      config.Default().Detector,
+	}
+}
+
+// Check is the verdict of one checker.
+type Check struct {
+	Name  string
+	Valid bool
+	// Advisory checks test a property the system does not promise: they are reported but do not fail the run
+	Advisory bool
+	Detail   string
+}
+
+func (c Check) String() string {
+	switch {
+	case c.Valid:
+		return "valid"
+	case c.Advisory:
+		return "invalid, not promised by the system: " + c.Detail
+	}
+	return "invalid: " + c.Detail
+}
+
+// Report is the outcome of a randomized run.
+type Report struct {
+	Seed   int
+	System string
+	// OK and Failed count the client operations that completed and the ones that failed or were refused
+	OK, Failed int
+	// Faults describes what the nemesis did, in order
+	Faults  []string
+	History []linearizability.KVOperation
+	Checks  []Check
+}
+
+// Valid reports whether every checker that is not advisory passed.
+func (r Report) Valid() bool {
+	return r.Err() == nil
+}
+
+// Err returns ErrCheckFailed with the failed checks and the seed to reproduce the run, or nil if the run is valid.
+func (r Report) Err() error {
+	var failed []string
+	for _, c := range r.Checks {
+		if !c.Valid && !c.Advisory {
+			failed = append(failed, c.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not %s, reproduce with -seed %d", ErrCheckFailed, r.System, strings.Join(failed, " nor "), r.Seed)
+}
+
+func (r Report) String() string {
+	checks := make([]string, len(r.Checks))
+	for i, c := range r.Checks {
+		checks[i] = fmt.Sprintf("%s=%v", c.Name, c.Valid)
+	}
+	return fmt.Sprintf("%s seed=%d: %d ok, %d failed, %d faults; %s",
+		r.System, r.Seed, r.OK, r.Failed, len(r.Faults), strings.Join(checks, " "))
+}
+
+// RunJepsen runs a workload on a cluster connected by the network cfg describes, with the faults of the workload.
+func RunJepsen(ctx context.Context, cfg config.Network, w Workload) (Report, error) {
+	report := Report{Seed: cfg.Seed, System: w.System}
+	if w.Nodes < 1 || w.Clients < 1 || w.Keys < 1 {
+		return report, fmt.Errorf("workload needs nodes, clients and keys, got %d, %d and %d", w.Nodes, w.Clients, w.Keys)
+	}
+	ids := make([]string, w.Nodes)
+	for i := range ids {
+		ids[i] = string(rune('A' + i))
+	}
+	c := newCluster(cfg, ids...)
+	switch w.System {
+	case "raft", "paxos":
+		if err := c.StartConsensus(w.System); err != nil {
+			return report, err
+		}
+	case "quorum":
+		c.ReplicationFactor = min(3, w.Nodes)
+	case "ca":
+		if err := c.StartFailureDetector(w.Detector); err != nil {
+			return report, err
+		}
+	default:
+		return report, fmt.Errorf("unknown system %q, want raft, paxos, quorum or ca", w.System)
+	}
+	system := systemJepsen + " " + w.System
+	nem, err := newNemesis(c, system, w.Faults)
+	if err != nil {
+		return report, err
+	}
+
+	stopped := false
+	nem.schedule(ctx, w.FaultInterval, func() bool { return stopped })
+	for i := 0; i < w.Clients; i++ {
+		c.runClient(w, ids[i%len(ids)], &stopped, &report)
+	}
+	if err := c.Run(ctx, w.Duration); err != nil {
+		return report, err
+	}
+
+	// Let the operations in flight finish and the repaired cluster settle before judging it
+	stopped = true
+	nem.heal()
+	if err := c.Run(ctx, 4*c.Timeout); err != nil {
+		return report, err
+	}
+
+	report.Faults = nem.log
+	report.History = c.History()
+	linearizable, err := checkLinearizable(ctx, report.History)
+	if err != nil {
+		return report, err
+	}
+	linearizable.Advisory = w.System == "quorum"
+	report.Checks = []Check{linearizable, checkConverged(c, w.Keys)}
+	return report, nil
+}
+
+// runClient keeps one operation at a time running on nodeID: a put of a unique value or a get, on a random key,
+// each after a short random pause, until stopped.
+func (c *Cluster) runClient(w Workload, nodeID string, stopped *bool, report *Report) {
+	rng := c.net.Rand()
+	pause := Uniform{Max: 20 * time.Millisecond}
+	writes := 0
+	var next func()
+	finished := func(err error) {
+		if err != nil {
+			report.Failed++
+		} else {
+			report.OK++
+		}
+		c.net.After(pause.Sample(rng), next)
+	}
+	next = func() {
+		if *stopped {
+			return
+		}
+		in := linearizability.KVInput{Op: "get", Key: jepsenKey(rng.Intn(w.Keys))}
+		if rng.Intn(2) == 0 {
+			writes++
+			in.Op, in.Value = "put", fmt.Sprintf("%s%d", nodeID, writes)
+		}
+		if c.net.Crashed(nodeID) {
+			// The connection is refused: the operation never started
+			finished(ErrCrashed)
+			return
+		}
+		c.clientOp(w, c.Node(nodeID), in, finished)
+	}
+	c.net.After(pause.Sample(rng), next)
+}
+
+// clientOp runs in on n the way clients of the system do; the operation is recorded in the history.
+func (c *Cluster) clientOp(w Workload, n *Node, in linearizability.KVInput, done func(error)) {
+	switch w.System {
+	case "raft", "paxos":
+		record := c.invoke(n.ID, in)
+		n.consensus.Propose(Command{Op: in.Op, Key: in.Key, Value: in.Value}, func(result string, err error) {
+			record(result, err)
+			done(err)
+		})
+	case "quorum":
+		if in.Op == "put" {
+			// Dynamo clients write with the context of the versions the coordinator knows
+			n.putQuorum(in.Key, in.Value, mergeContext(n.store[in.Key]), w.W, done)
+			return
+		}
+		n.getQuorum(in.Key, w.R, func(_ []Version, err error) { done(err) })
+	case "ca":
+		if partitionedFrom(c, n.ID) {
+			done(ErrUnavailable)
+			return
+		}
+		if in.Op == "put" {
+			n.writeSync(in.Key, in.Value, done)
+			return
+		}
+		n.readSync(in.Key, func(_ string, err error) { done(err) })
+	}
+}
+
+func jepsenKey(i int) string { return fmt.Sprintf("k%d", i) }
+
+func checkLinearizable(ctx context.Context, history []linearizability.KVOperation) (Check, error) {
+	r, err := linearizability.Check(ctx, linearizability.KVModel(), history)
+	if err != nil {
+		return Check{}, err
+	}
+	check := Check{Name: "linearizable", Valid: r.Linearizable}
+	if !r.Linearizable {
+		check.Detail = r.String()
+	}
+	return check, nil
+}
+
+// checkConverged compares the values the replicas of every key hold.
+func checkConverged(c *Cluster, keys int) Check {
+	var diverged []string
+	for i := 0; i < keys; i++ {
+		key := jepsenKey(i)
+		var values, replicas []string
+		for _, id := range c.PreferenceList(key, c.ReplicationFactor) {
+			v, _ := c.Node(id).Get(key)
+			values = append(values, v)
+			replicas = append(replicas, fmt.Sprintf("%s=%q", id, v))
+		}
+		if len(slices.Compact(values)) > 1 {
+			diverged = append(diverged, key+" "+strings.Join(replicas, " "))
+		}
+	}
+	check := Check{Name: "converged", Valid: len(diverged) == 0}
+	if len(diverged) > 0 {
+		check.Detail = "replicas differ on " + strings.Join(diverged, ", ")
+	}
+	return check
+}
+
+// SimulateJepsen runs a workload under faults and reports the verdict of every checker.
+// It fails with ErrCheckFailed, and the seed to reproduce the run, if any checker that is not advisory does.
+func SimulateJepsen(ctx context.Context, cfg config.Network, w Workload) error {
+	r, err := RunJepsen(ctx, cfg, w)
+	if err != nil {
+		return err
+	}
+	system := systemJepsen + " " + w.System
+	events.Emit(ctx, events.Result{Name: system + " operations", Value: fmt.Sprintf("%d ok, %d failed", r.OK, r.Failed)})
+	for _, check := range r.Checks {
+		events.Emit(ctx, events.Result{Name: system + " " + check.Name, Value: check})
+	}
+	return r.Err()
+}
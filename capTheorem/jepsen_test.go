@@ -0,0 +1,100 @@
+package capTheorem
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+)
+
+func jepsenNetwork(seed int) config.Network {
+	return config.Network{Seed: seed, Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond}
+}
+
+func TestJepsenReproducible(t *testing.T) {
+	w := DefaultWorkload("raft")
+	w.Duration = 3 * time.Second
+	a, err := RunJepsen(context.Background(), jepsenNetwork(7), w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := RunJepsen(context.Background(), jepsenNetwork(7), w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.Faults, b.Faults) || !reflect.DeepEqual(a.History, b.History) {
+		t.Error("two runs with the same seed differ")
+	}
+	if len(a.Faults) == 0 || len(a.History) == 0 {
+		t.Errorf("run injected %d faults and recorded %d operations", len(a.Faults), len(a.History))
+	}
+}
+
+func TestJepsenConsensusIsLinearizable(t *testing.T) {
+	for _, system := range []string{"raft", "paxos"} {
+		for seed := 1; seed <= 5; seed++ {
+			w := DefaultWorkload(system)
+			w.Duration = 5 * time.Second
+			r, err := RunJepsen(context.Background(), jepsenNetwork(seed), w)
+			if err != nil {
+				t.Fatalf("%s seed %d: %v", system, seed, err)
+			}
+			if !r.Valid() {
+				t.Errorf("%s seed %d: %v %v", system, seed, r, r.Checks)
+			}
+		}
+	}
+}
+
+// Seeds 3 and 32 are regressions: writes two-phase commit aborted were left open in the history,
+// and the search for a linearization never ended.
+func TestJepsenCALinearizable(t *testing.T) {
+	for _, seed := range []int{1, 2, 3, 32} {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		r, err := RunJepsen(ctx, jepsenNetwork(seed), DefaultWorkload("ca"))
+		cancel()
+		if err != nil {
+			t.Fatalf("ca seed %d: %v", seed, err)
+		}
+		if !r.Valid() {
+			t.Errorf("ca seed %d: %v %v", seed, r, r.Checks)
+		}
+	}
+}
+
+// Seed 1 is a regression: sloppy quorums with R+W>N still let concurrent writes be read out of order.
+func TestJepsenQuorumNotLinearizable(t *testing.T) {
+	r, err := RunJepsen(context.Background(), jepsenNetwork(1), DefaultWorkload("quorum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Checks[0].Valid {
+		t.Error("quorum history checked linearizable; want a violation")
+	}
+	if !r.Valid() {
+		t.Errorf("quorum run invalid: %v %v; want only the advisory check to fail", r, r.Checks)
+	}
+}
+
+func TestReportErr(t *testing.T) {
+	r := Report{Seed: 7, System: "raft", Checks: []Check{{Name: "linearizable"}, {Name: "converged", Valid: true}}}
+	if err := r.Err(); !errors.Is(err, ErrCheckFailed) || !strings.Contains(err.Error(), "-seed 7") {
+		t.Errorf("Err = %v; want %v with the seed", err, ErrCheckFailed)
+	}
+	r.Checks[0].Advisory = true
+	if err := r.Err(); err != nil {
+		t.Errorf("Err = %v with only an advisory check failed; want nil", err)
+	}
+}
+
+func TestJepsenUnknownFault(t *testing.T) {
+	w := DefaultWorkload("raft")
+	w.Faults = []string{"meteor"}
+	if _, err := RunJepsen(context.Background(), jepsenNetwork(1), w); err == nil {
+		t.Error("RunJepsen accepted an unknown fault")
+	}
+}
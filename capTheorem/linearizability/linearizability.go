@@ -0,0 +1,260 @@
+package linearizability
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Linearizability checking
+//A history is linearizable if every operation can be given a point between its call and its return
+//so that, taken in that order, the operations are a valid sequential run of the model.
+//The checker follows Wing & Gong with Lowe's memoization, as Porcupine does: it tries to linearize the operation calls in time order,
+//backtracks when an operation returns before being linearized, and caches (linearized set, state) pairs it already explored.
+//Histories are first split into independent parts, such as one per key, each checked on its own.
+//The search is exponential in the worst case, so it stops when its context is cancelled.
+
+// Operation is a client operation with the times it was called and returned.
+// An operation that never returned, such as a write that timed out, has a zero Return: it may take effect at any later point.
+type Operation[I, O any] struct {
+	Client string
+	Input  I
+	Output O
+	Call   time.Time
+	Return time.Time
+}
+
+func (o Operation[I, O]) String() string {
+	if o.Return.IsZero() {
+		return fmt.Sprintf("%s: %v (no reply)", o.Client, o.Input)
+	}
+	if out := fmt.Sprint(o.Output); out != "" {
+		return fmt.Sprintf("%s: %v -> %s", o.Client, o.Input, out)
+	}
+	return fmt.Sprintf("%s: %v", o.Client, o.Input)
+}
+
+// returned returns the time the operation returned, the end of time for one that never did.
+func (o Operation[I, O]) returned() time.Time {
+	if o.Return.IsZero() {
+		return time.Unix(0, math.MaxInt64)
+	}
+	return o.Return
+}
+
+// Model is a sequential specification of a data type with states S, inputs I and outputs O.
+type Model[S comparable, I, O any] struct {
+	// Init returns the initial state
+	Init func() S
+	// Step reports whether output is a valid answer to input in state, and returns the next state
+	Step func(state S, input I, output O) (bool, S)
+	// Partition optionally splits a history into parts that can be checked independently
+	Partition func(history []Operation[I, O]) [][]Operation[I, O]
+}
+
+// Result is the verdict of Check.
+type Result[I, O any] struct {
+	Linearizable bool
+	Operations   int
+	// Order is the longest sequence of operations that could be linearized in the first part that failed
+	Order []Operation[I, O]
+	// Stuck are the operations of that part that could come next in real time, but none of which the model accepts
+	Stuck []Operation[I, O]
+}
+
+func (r Result[I, O]) String() string {
+	if r.Linearizable {
+		return fmt.Sprintf("linearizable (%d operations)", r.Operations)
+	}
+	order := make([]string, len(r.Order))
+	for i, op := range r.Order {
+		order[i] = op.String()
+	}
+	stuck := make([]string, len(r.Stuck))
+	for i, op := range r.Stuck {
+		stuck[i] = op.String()
+	}
+	return fmt.Sprintf("not linearizable (%d operations): after [%s], none of [%s] can come next",
+		r.Operations, strings.Join(order, "; "), strings.Join(stuck, "; "))
+}
+
+// Check decides whether history is linearizable with respect to model.
+// It returns the context's error, and no verdict, if ctx is cancelled before the search ends.
+func Check[S comparable, I, O any](ctx context.Context, model Model[S, I, O], history []Operation[I, O]) (Result[I, O], error) {
+	parts := [][]Operation[I, O]{history}
+	if model.Partition != nil {
+		parts = model.Partition(history)
+	}
+	for _, part := range parts {
+		ok, order, stuck, err := checkPart(ctx, model, part)
+		if err != nil {
+			return Result[I, O]{}, err
+		}
+		if !ok {
+			return Result[I, O]{Operations: len(history), Order: order, Stuck: stuck}, nil
+		}
+	}
+	return Result[I, O]{Linearizable: true, Operations: len(history)}, nil
+}
+
+// entry is the call or the return of an operation in a doubly linked list ordered by time.
+type entry struct {
+	op         int
+	call       bool
+	match      *entry // the return of a call
+	prev, next *entry
+}
+
+// lift removes a call and its return from the list.
+func (e *entry) lift() {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	r := e.match
+	r.prev.next = r.next
+	if r.next != nil {
+		r.next.prev = r.prev
+	}
+}
+
+// unlift puts back a call and its return removed by lift.
+func (e *entry) unlift() {
+	r := e.match
+	r.prev.next = r
+	if r.next != nil {
+		r.next.prev = r
+	}
+	e.prev.next = e
+	e.next.prev = e
+}
+
+type cacheKey[S comparable] struct {
+	linearized string
+	state      S
+}
+
+type frame[S comparable] struct {
+	call  *entry
+	state S
+}
+
+// checkInterval is how many steps of the search run between two looks at the context.
+const checkInterval = 1 << 12
+
+func checkPart[S comparable, I, O any](ctx context.Context, model Model[S, I, O], ops []Operation[I, O]) (bool, []Operation[I, O], []Operation[I, O], error) {
+	head := buildList(ops)
+	linearized := make([]byte, (len(ops)+7)/8)
+	cache := make(map[cacheKey[S]]bool)
+	var stack, longest []frame[S]
+
+	state := model.Init()
+	e := head.next
+	for steps := 1; head.next != nil; steps++ {
+		if steps%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return false, nil, nil, err
+			}
+		}
+		if e.call {
+			op := ops[e.op]
+			if ok, next := model.Step(state, op.Input, op.Output); ok {
+				linearized[e.op/8] |= 1 << (e.op % 8)
+				key := cacheKey[S]{string(linearized), next}
+				if !cache[key] {
+					cache[key] = true
+					stack = append(stack, frame[S]{e, state})
+					if len(stack) > len(longest) {
+						longest = append(longest[:0], stack...)
+					}
+					state = next
+					e.lift()
+					e = head.next
+					continue
+				}
+				linearized[e.op/8] &^= 1 << (e.op % 8)
+			}
+			e = e.next
+			continue
+		}
+		// An operation returned before any order could include it: undo the last choice
+		if len(stack) == 0 {
+			return false, linearization(ops, longest), stuck(ops, longest), nil
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state = top.state
+		linearized[top.call.op/8] &^= 1 << (top.call.op % 8)
+		top.call.unlift()
+		e = top.call.next
+	}
+	return true, nil, nil, nil
+}
+
+// buildList returns the head of a list of the calls and returns of ops in time order.
+// At equal times calls come first, so the operations are considered concurrent.
+func buildList[I, O any](ops []Operation[I, O]) *entry {
+	type event struct {
+		at time.Time
+		e  *entry
+	}
+	events := make([]event, 0, 2*len(ops))
+	for i, op := range ops {
+		ret := &entry{op: i}
+		call := &entry{op: i, call: true, match: ret}
+		events = append(events, event{op.Call, call}, event{op.returned(), ret})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].at.Equal(events[j].at) {
+			return events[i].at.Before(events[j].at)
+		}
+		return events[i].e.call && !events[j].e.call
+	})
+	head := &entry{op: -1}
+	prev := head
+	for _, ev := range events {
+		prev.next = ev.e
+		ev.e.prev = prev
+		prev = ev.e
+	}
+	return head
+}
+
+func linearization[S comparable, I, O any](ops []Operation[I, O], frames []frame[S]) []Operation[I, O] {
+	out := make([]Operation[I, O], len(frames))
+	for i, f := range frames {
+		out[i] = ops[f.call.op]
+	}
+	return out
+}
+
+// stuck returns the operations left after the frames that were called before any of them returned.
+func stuck[S comparable, I, O any](ops []Operation[I, O], frames []frame[S]) []Operation[I, O] {
+	done := make(map[int]bool, len(frames))
+	for _, f := range frames {
+		done[f.call.op] = true
+	}
+	var left []Operation[I, O]
+	for i, op := range ops {
+		if !done[i] {
+			left = append(left, op)
+		}
+	}
+	if len(left) == 0 {
+		return nil
+	}
+	first := left[0].returned()
+	for _, op := range left {
+		if op.returned().Before(first) {
+			first = op.returned()
+		}
+	}
+	var out []Operation[I, O]
+	for _, op := range left {
+		if !op.Call.After(first) {
+			out = append(out, op)
+		}
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package linearizability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// op builds an operation called at call and returned at ret milliseconds, or never if ret < 0.
+func op(client, kind, key, value string, call, ret int) KVOperation {
+	o := KVOperation{Client: client, Input: KVInput{Op: kind, Key: key}, Call: t0.Add(time.Duration(call) * time.Millisecond)}
+	if kind == "put" {
+		o.Input.Value = value
+	} else {
+		o.Output = value
+	}
+	if ret >= 0 {
+		o.Return = t0.Add(time.Duration(ret) * time.Millisecond)
+	}
+	return o
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []KVOperation
+		want    bool
+	}{
+		{"empty", nil, true},
+		{"sequential", []KVOperation{
+			op("A", "put", "x", "1", 0, 10),
+			op("B", "get", "x", "1", 20, 30),
+		}, true},
+		{"stale read after the write returned", []KVOperation{
+			op("A", "put", "x", "1", 0, 10),
+			op("B", "get", "x", "", 20, 30),
+		}, false},
+		{"read concurrent with the write sees either value", []KVOperation{
+			op("A", "put", "x", "1", 0, 100),
+			op("B", "get", "x", "", 10, 20),
+			op("C", "get", "x", "1", 30, 40),
+		}, true},
+		{"reads go back in time", []KVOperation{
+			op("A", "put", "x", "1", 0, 100),
+			op("B", "get", "x", "1", 10, 20),
+			op("C", "get", "x", "", 30, 40),
+		}, false},
+		{"write without reply may take effect later", []KVOperation{
+			op("A", "put", "x", "1", 0, -1),
+			op("B", "get", "x", "", 10, 20),
+			op("C", "get", "x", "1", 500, 510),
+		}, true},
+		{"two concurrent writes seen in different orders", []KVOperation{
+			op("A", "put", "x", "1", 0, 50),
+			op("B", "put", "x", "2", 0, 50),
+			op("C", "get", "x", "1", 60, 70),
+			op("D", "get", "x", "2", 80, 90),
+		}, false},
+		{"keys are independent", []KVOperation{
+			op("A", "put", "x", "1", 0, 10),
+			op("B", "put", "y", "2", 20, 30),
+			op("C", "get", "x", "1", 40, 50),
+			op("C", "get", "y", "2", 40, 50),
+		}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Check(context.Background(), KVModel(), tt.history)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.Linearizable != tt.want {
+			t.Errorf("%s: Check = %v; want linearizable=%v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCounterexample(t *testing.T) {
+	history := []KVOperation{
+		op("A", "put", "x", "1", 0, 10),
+		op("B", "get", "x", "1", 20, 30),
+		op("C", "get", "x", "", 40, 50),
+	}
+	got, err := Check(context.Background(), RegisterModel(), history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Linearizable || len(got.Order) != 2 || len(got.Stuck) != 1 || got.Stuck[0].Client != "C" {
+		t.Fatalf("Check = %v; want the stale read of C as counterexample", got)
+	}
+}
+
+func TestCheckManyConcurrentOperations(t *testing.T) {
+	// Ten clients write at the same time, then each read sees the last write; the search has to stay fast
+	var history []KVOperation
+	for i := 0; i < 10; i++ {
+		history = append(history, op(fmt.Sprint(i), "put", "x", fmt.Sprint(i), 0, 100))
+	}
+	for i := 0; i < 10; i++ {
+		history = append(history, op(fmt.Sprint(i), "get", "x", "7", 200+10*i, 205+10*i))
+	}
+	got, err := Check(context.Background(), RegisterModel(), history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Linearizable {
+		t.Errorf("Check = %v; want linearizable", got)
+	}
+}
+
+func TestCheckCancelled(t *testing.T) {
+	// Twenty concurrent writes that no read can order: the search has to try every order before giving up
+	var history []KVOperation
+	for i := 0; i < 20; i++ {
+		history = append(history, op(fmt.Sprint(i), "put", "x", fmt.Sprint(i), 0, 100))
+	}
+	for i := 0; i < 20; i++ {
+		history = append(history, op(fmt.Sprint(i), "get", "x", fmt.Sprint(i), 200, 300))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Check(ctx, RegisterModel(), history); !errors.Is(err, context.Canceled) {
+		t.Errorf("Check with a cancelled context = %v; want %v", err, context.Canceled)
+	}
+}
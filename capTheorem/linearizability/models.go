@@ -0,0 +1,59 @@
+package linearizability
+
+import (
+	"fmt"
+	"sort"
+)
+
+// KVInput is a put or a get of a key.
+type KVInput struct {
+	Op    string // "put" or "get"
+	Key   string
+	Value string
+}
+
+func (in KVInput) String() string {
+	if in.Op == "put" {
+		return fmt.Sprintf("put %s=%q", in.Key, in.Value)
+	}
+	return "get " + in.Key
+}
+
+// KVOperation is an operation on a key/value store; the output of a get is the value read.
+type KVOperation = Operation[KVInput, string]
+
+// RegisterModel is a single read/write register, initially empty: a get returns the value of the last put.
+// The key of the inputs is ignored.
+func RegisterModel() Model[string, KVInput, string] {
+	return Model[string, KVInput, string]{
+		Init: func() string { return "" },
+		Step: func(state string, in KVInput, out string) (bool, string) {
+			if in.Op == "put" {
+				return true, in.Value
+			}
+			return out == state, state
+		},
+	}
+}
+
+// KVModel is a map of independent registers, checked one key at a time.
+func KVModel() Model[string, KVInput, string] {
+	m := RegisterModel()
+	m.Partition = func(history []KVOperation) [][]KVOperation {
+		byKey := make(map[string][]KVOperation)
+		for _, op := range history {
+			byKey[op.Input.Key] = append(byKey[op.Input.Key], op)
+		}
+		keys := make([]string, 0, len(byKey))
+		for key := range byKey {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([][]KVOperation, len(keys))
+		for i, key := range keys {
+			parts[i] = byKey[key]
+		}
+		return parts
+	}
+	return m
+}
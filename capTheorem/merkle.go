@@ -0,0 +1,196 @@
+package capTheorem
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+//Merkle-tree anti-entropy
+//Each node summarizes its store as a Merkle tree: keys are spread over 2^depth leaf ranges by hash,
+//a leaf hashes the versions of its keys and every parent hashes its two children.
+//Two replicas with the same root hold the same data; otherwise only the branches whose hashes differ are walked,
+//and only the keys of the differing leaf ranges are exchanged.
+//A background process runs a session with one peer at a time, so replicas reconcile after a partition heals.
+
+const merkleDepth = 6 // 64 leaf ranges
+
+// MerkleTree stores its hashes in heap order: the root at 1 and the children of i at 2i and 2i+1.
+type MerkleTree []uint64
+
+// AntiEntropyStats counts the work done by anti-entropy sessions.
+type AntiEntropyStats struct {
+	Sessions        int `json:"sessions"`
+	InSync          int `json:"in_sync"`
+	HashesCompared  int `json:"hashes_compared"`
+	RangesRepaired  int `json:"ranges_repaired"`
+	KeysTransferred int `json:"keys_transferred"`
+}
+
+func (s AntiEntropyStats) String() string {
+	return fmt.Sprintf("sessions=%d in sync=%d hashes compared=%d ranges repaired=%d keys transferred=%d",
+		s.Sessions, s.InSync, s.HashesCompared, s.RangesRepaired, s.KeysTransferred)
+}
+
+func leafOf(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % (1 << merkleDepth))
+}
+
+// buildMerkle summarizes a store.
+func buildMerkle(store map[string][]Version) MerkleTree {
+	leaves := make([][]string, 1<<merkleDepth)
+	for key := range store {
+		leaves[leafOf(key)] = append(leaves[leafOf(key)], key)
+	}
+	tree := make(MerkleTree, 2<<merkleDepth)
+	for i, keys := range leaves {
+		sort.Strings(keys)
+		h := fnv.New64a()
+		for _, key := range keys {
+			fmt.Fprintf(h, "%s=", key)
+			for _, v := range store[key] {
+				fmt.Fprintf(h, "%q%s;", v.Value, v.Clock)
+			}
+		}
+		tree[1<<merkleDepth+i] = h.Sum64()
+	}
+	for i := 1<<merkleDepth - 1; i >= 1; i-- {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%d|%d", tree[2*i], tree[2*i+1])
+		tree[i] = h.Sum64()
+	}
+	return tree
+}
+
+// diff returns the leaf ranges where t and o differ, and how many hashes it compared.
+func (t MerkleTree) diff(o MerkleTree) (leaves []int, compared int) {
+	var walk func(i int)
+	walk = func(i int) {
+		compared++
+		if t[i] == o[i] {
+			return
+		}
+		if i >= 1<<merkleDepth {
+			leaves = append(leaves, i-1<<merkleDepth)
+			return
+		}
+		walk(2 * i)
+		walk(2*i + 1)
+	}
+	walk(1)
+	return leaves, compared
+}
+
+// keysIn returns the versions of every key of the store in the given leaf ranges.
+func keysIn(store map[string][]Version, leaves []int) map[string][]Version {
+	wanted := make(map[int]bool, len(leaves))
+	for _, l := range leaves {
+		wanted[l] = true
+	}
+	out := make(map[string][]Version)
+	for key, versions := range store {
+		if wanted[leafOf(key)] {
+			out[key] = versions
+		}
+	}
+	return out
+}
+
+// merkleTree opens a session: the initiator's whole tree.
+type merkleTree struct {
+	Tree MerkleTree
+}
+
+// merkleRepair answers with the keys of the differing ranges and asks for the initiator's.
+type merkleRepair struct {
+	Keys   map[string][]Version
+	Leaves []int
+}
+
+// merkleKeys closes a session with the initiator's keys of the differing ranges.
+type merkleKeys struct {
+	Keys map[string][]Version
+}
+
+// StartAntiEntropy makes every node run a session with its next peer every interval.
+func (c *Cluster) StartAntiEntropy(interval time.Duration) {
+	for i, n := range c.nodes {
+		var tick func()
+		tick = func() {
+			peers := c.peers(n.ID)
+			peer := peers[n.antiEntropyNext%len(peers)]
+			n.antiEntropyNext++
+			c.AntiEntropy.Sessions++
+			n.send(peer.ID, merkleTree{Tree: buildMerkle(n.store)})
+			c.net.After(interval, tick)
+		}
+		// Spread the nodes' sessions over the interval
+		c.net.After(interval*time.Duration(i+1)/time.Duration(len(c.nodes)), tick)
+	}
+}
+
+func (n *Node) onMerkleTree(from string, m merkleTree) {
+	leaves, compared := buildMerkle(n.store).diff(m.Tree)
+	stats := &n.cluster.AntiEntropy
+	stats.HashesCompared += compared
+	if len(leaves) == 0 {
+		stats.InSync++
+		return
+	}
+	stats.RangesRepaired += len(leaves)
+	keys := keysIn(n.store, leaves)
+	stats.KeysTransferred += len(keys)
+	n.send(from, merkleRepair{Keys: keys, Leaves: leaves})
+}
+
+func (n *Node) onMerkleRepair(from string, m merkleRepair) {
+	mine := keysIn(n.store, m.Leaves)
+	n.applyAll(m.Keys)
+	n.cluster.AntiEntropy.KeysTransferred += len(mine)
+	n.send(from, merkleKeys{Keys: mine})
+}
+
+// applyAll merges versions received from a peer, in key order so runs are reproducible.
+func (n *Node) applyAll(keys map[string][]Version) {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		for _, v := range keys[key] {
+			n.apply(key, v)
+		}
+	}
+}
+
+// since returns the work done after before was taken.
+func (s AntiEntropyStats) since(before AntiEntropyStats) AntiEntropyStats {
+	return AntiEntropyStats{
+		Sessions:        s.Sessions - before.Sessions,
+		InSync:          s.InSync - before.InSync,
+		HashesCompared:  s.HashesCompared - before.HashesCompared,
+		RangesRepaired:  s.RangesRepaired - before.RangesRepaired,
+		KeysTransferred: s.KeysTransferred - before.KeysTransferred,
+	}
+}
+
+// DivergedKeys returns how many keys are not identical on every node.
+func (c *Cluster) DivergedKeys() int {
+	keys := make(map[string]bool)
+	for _, n := range c.nodes {
+		for key := range n.store {
+			keys[key] = true
+		}
+	}
+	diverged := 0
+	for key := range keys {
+		if c.Diverged(key) {
+			diverged++
+		}
+	}
+	return diverged
+}
@@ -0,0 +1,65 @@
+package capTheorem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+func TestMerkleDiff(t *testing.T) {
+	store := make(map[string][]Version)
+	for i := 0; i < 500; i++ {
+		store[catalogKey(i)] = []Version{{Value: "v1", Clock: VectorClock{"A": 1}}}
+	}
+	other := make(map[string][]Version, len(store))
+	for k, v := range store {
+		other[k] = v
+	}
+	other[catalogKey(7)] = []Version{{Value: "v2", Clock: VectorClock{"A": 2}}}
+	other["new-key"] = []Version{{Value: "v1", Clock: VectorClock{"B": 1}}}
+
+	if leaves, _ := buildMerkle(store).diff(buildMerkle(store)); len(leaves) != 0 {
+		t.Errorf("identical stores differ in %v", leaves)
+	}
+	leaves, compared := buildMerkle(store).diff(buildMerkle(other))
+	want := map[int]bool{leafOf(catalogKey(7)): true, leafOf("new-key"): true}
+	if len(leaves) != len(want) {
+		t.Fatalf("diff = %v; want leaves %v", leaves, want)
+	}
+	for _, l := range leaves {
+		if !want[l] {
+			t.Errorf("diff reported leaf %d, which did not change", l)
+		}
+	}
+	if all := 2 << merkleDepth; compared >= all/2 {
+		t.Errorf("compared %d hashes; want far fewer than the %d of the tree", compared, all)
+	}
+}
+
+func TestAntiEntropyRepairsOnlyDifferences(t *testing.T) {
+	ctx := events.WithSink(context.Background(), events.Discard)
+	c := newCluster(config.Network{Seed: 3, Latency: 10 * time.Millisecond}, "A", "B", "C")
+	if err := loadCatalog(ctx, c, "A", 300); err != nil {
+		t.Fatal(err)
+	}
+	c.Network().Partition([]string{"A", "B"}, []string{"C"})
+	updateCatalog(c, "A", 1, 2)
+	updateCatalog(c, "C", 250)
+	c.Network().RunFor(time.Second)
+	if got := c.DivergedKeys(); got != 3 {
+		t.Fatalf("DivergedKeys during partition = %d; want 3", got)
+	}
+
+	c.Network().Heal()
+	c.StartAntiEntropy(200 * time.Millisecond)
+	c.Network().RunFor(2 * time.Second)
+	if got := c.DivergedKeys(); got != 0 {
+		t.Errorf("DivergedKeys after anti-entropy = %d; want 0", got)
+	}
+	if got := c.AntiEntropy.KeysTransferred; got == 0 || got > 60 {
+		t.Errorf("anti-entropy transferred %d keys; want only the keys of the differing ranges", got)
+	}
+}
@@ -1,6 +1,398 @@
 package capTheorem
 
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"GoBestPratices/capTheorem/crdt"
+	"GoBestPratices/capTheorem/linearizability"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+	"GoBestPratices/logging"
+)
+
+//Cluster model
+//A Cluster is a set of Nodes, each holding its own copy of the data.
+//Nodes only talk to each other through messages on the simulated Network, so partitions, delays and losses affect replication.
+//The CP, AP and CA simulations differ only in how they replicate and what they refuse when peers cannot be reached.
+
+// dataKey is the key the CAP simulations read and write.
+const dataKey = "data"
+
 var (
-	data        string
-	isPartition bool // Simulate network partition
+	// ErrUnavailable is returned when a node refuses an operation to protect consistency.
+	ErrUnavailable = errors.New("unavailable during network partition")
+	// ErrTimeout is returned when an operation did not complete in time.
+	ErrTimeout = errors.New("operation timed out")
+	// ErrCrashed is returned for the operations a node was running when it crashed.
+	ErrCrashed = errors.New("node crashed")
 )
+
+// Node is one replica in a Cluster with its own key/value store.
+type Node struct {
+	ID      string
+	cluster *Cluster
+	// store holds the versions of every key, several when concurrent writes conflicted
+	store map[string][]Version
+	// writes counts the writes of each key this node coordinated, its entry in their vector clocks
+	writes map[string]uint64
+
+	// Synchronous replication state, see replication.go
+	seq      uint64
+	ops      map[opID]*writeOp
+	staged   map[string]stagedWrite
+	finished map[opID]bool
+	blocked  map[string][]func()
+
+	// Quorum operations this node coordinates, see quorum.go
+	quorums map[uint64]*quorumOp
+
+	// CRDT keys and the deltas each peer has not acknowledged, see crdtReplication.go
+	crdts     map[string]crdt.State
+	outbox    map[string]map[string]crdt.State
+	outboxSeq map[string]uint64
+	gossiping bool
+
+	// Hints kept for unreachable replicas, see hintedHandoff.go
+	hints      map[string][]hint
+	handingOff bool
+
+	// Consensus engine and the sessions of the nodes whose commands it applied to the store, see stateMachine.go
+	consensus Replicator
+	sessions  map[string]session
+
+	// Causal writes waiting for acknowledgements or for their dependencies, see causal.go
+	causalSends map[uint64]map[string]bool
+	delayed     []delayedPut
+
+	// detectors hold the phi-accrual failure detector of every peer, nil until started, see failureDetector.go
+	detectors map[string]*phiAccrual
+
+	// antiEntropyNext picks the peer of the next anti-entropy session, see merkle.go
+	antiEntropyNext int
+}
+
+// Get returns the value the node holds for key, with conflicting siblings joined by " | ".
+func (n *Node) Get(key string) (string, bool) {
+	siblings, ok := n.store[key]
+	return strings.Join(siblingValues(siblings), " | "), ok
+}
+
+// Siblings returns every version the node holds for key.
+func (n *Node) Siblings(key string) []Version {
+	return n.store[key]
+}
+
+// readLocal serves a client read of key from this replica alone, recorded in the history.
+func (n *Node) readLocal(key string) []Version {
+	record := n.cluster.invoke(n.ID, linearizability.KVInput{Op: "get", Key: key})
+	versions := n.store[key]
+	record(strings.Join(siblingValues(versions), " | "), nil)
+	return versions
+}
+
+// apply merges v into the versions of key, dropping the ones it supersedes.
+func (n *Node) apply(key string, v Version) {
+	n.store[key] = addVersion(n.store[key], v)
+}
+
+// newVersion returns a version of key written by this node after everything in seen.
+func (n *Node) newVersion(key, value string, seen VectorClock) Version {
+	clock := seen.Copy()
+	n.writes[key] = max(n.writes[key], clock[n.ID]) + 1
+	clock[n.ID] = n.writes[key]
+	return Version{Value: value, Clock: clock}
+}
+
+// send delivers payload to another node over the network; messages to itself skip the links.
+func (n *Node) send(to string, payload any) {
+	if to == n.ID {
+		if n.cluster.net.Crashed(n.ID) {
+			return
+		}
+		msg := Message{From: n.ID, To: n.ID, Payload: payload, Sent: n.cluster.net.Now()}
+		n.cluster.net.After(0, func() { n.handle(msg) })
+		return
+	}
+	n.cluster.net.Send(n.ID, to, payload)
+}
+
+// now returns the time on the node's clock, which may be skewed.
+func (n *Node) now() time.Time {
+	return n.cluster.net.Clock(n.ID)
+}
+
+func (n *Node) broadcast(payload any) {
+	for _, peer := range n.cluster.peers(n.ID) {
+		n.send(peer.ID, payload)
+	}
+}
+
+func (n *Node) handle(m Message) {
+	switch p := m.Payload.(type) {
+	case prepare:
+		n.onPrepare(m.From, p)
+	case vote:
+		n.onVote(m.From, p)
+	case decision:
+		n.onDecision(m.From, p)
+	case decisionAck:
+		n.onDecisionAck(m.From, p)
+	case quorumPut:
+		n.apply(p.Key, p.Version)
+		n.send(m.From, quorumReply{Req: p.Req})
+	case quorumGet:
+		n.send(m.From, quorumReply{Req: p.Req, Versions: n.store[p.Key]})
+	case quorumReply:
+		n.onQuorumReply(m.From, p)
+	case deltaBatch:
+		n.onDeltaBatch(m.From, p)
+	case deltaAck:
+		n.onDeltaAck(m.From, p)
+	case merkleTree:
+		n.onMerkleTree(m.From, p)
+	case merkleRepair:
+		n.onMerkleRepair(m.From, p)
+	case merkleKeys:
+		n.applyAll(p.Keys)
+	case hintedPut:
+		n.onHintedPut(m.From, p)
+	case hintReplay:
+		n.onHintReplay(m.From, p)
+	case hintAck:
+		n.onHintAck(m.From, p)
+	case causalPut:
+		n.onCausalPut(m.From, p)
+	case causalAck:
+		n.onCausalAck(m.From, p)
+	case heartbeat:
+		n.onHeartbeat(m.From)
+	case readRepair:
+		for _, v := range p.Versions {
+			n.apply(p.Key, v)
+		}
+	default:
+		if n.consensus != nil {
+			n.consensus.receive(m.From, m.Payload)
+		}
+	}
+}
+
+// Cluster is a set of nodes connected by a simulated network.
+type Cluster struct {
+	net   *Network
+	nodes []*Node
+	// ReplicationFactor is how many nodes store each key in quorum mode (N)
+	ReplicationFactor int
+	// Timeout bounds how long a client waits for an operation
+	Timeout time.Duration
+	// Retry is how often unanswered messages are sent again
+	Retry time.Duration
+	// GossipInterval is how often nodes ship pending CRDT deltas
+	GossipInterval time.Duration
+	// history records the client operations, see history.go
+	history []*linearizability.KVOperation
+	// AntiEntropy counts the work of the Merkle-tree sessions
+	AntiEntropy AntiEntropyStats
+	// HintedHandoff lets fallback nodes accept quorum writes for unreachable replicas
+	HintedHandoff bool
+	// ReadRepair makes quorum reads update the replicas that answered with stale versions
+	ReadRepair bool
+	// HintInterval is how often nodes try to deliver the hints they keep
+	HintInterval time.Duration
+	// Handoff counts the hints and read repairs
+	Handoff HandoffStats
+	// ElectionTimeout is the minimum time a follower waits for a leader before starting an election
+	ElectionTimeout time.Duration
+	// Heartbeat is how often a leader contacts its followers
+	Heartbeat time.Duration
+	// Commands measures the commands that went through consensus
+	Commands CommandStats
+	// SnapshotThreshold is how many applied log entries, or Paxos slots, a node keeps before compacting them into a snapshot
+	SnapshotThreshold int
+	// PartitionOracle lets nodes without a failure detector read the network's partition table instead.
+	// Only tests set it, to exercise code that reacts to suspicions without waiting for a detector
+	PartitionOracle bool
+	// Detector configures the failure detector, once started
+	Detector config.Detector
+	// Causal counts the writes replicated in causal mode
+	Causal CausalStats
+}
+
+// NewCluster returns a cluster with one node per id, attached to net.
+func NewCluster(net *Network, ids ...string) *Cluster {
+	c := &Cluster{
+		net:               net,
+		Timeout:           500 * time.Millisecond,
+		Retry:             50 * time.Millisecond,
+		GossipInterval:    100 * time.Millisecond,
+		HintInterval:      100 * time.Millisecond,
+		ElectionTimeout:   150 * time.Millisecond,
+		Heartbeat:         50 * time.Millisecond,
+		SnapshotThreshold: 1000,
+	}
+	for _, id := range ids {
+		n := &Node{
+			ID:          id,
+			cluster:     c,
+			store:       make(map[string][]Version),
+			writes:      make(map[string]uint64),
+			ops:         make(map[opID]*writeOp),
+			staged:      make(map[string]stagedWrite),
+			finished:    make(map[opID]bool),
+			blocked:     make(map[string][]func()),
+			quorums:     make(map[uint64]*quorumOp),
+			crdts:       make(map[string]crdt.State),
+			outbox:      make(map[string]map[string]crdt.State),
+			outboxSeq:   make(map[string]uint64),
+			hints:       make(map[string][]hint),
+			sessions:    make(map[string]session),
+			causalSends: make(map[uint64]map[string]bool),
+		}
+		c.nodes = append(c.nodes, n)
+		net.Register(id, n.handle)
+	}
+	c.ReplicationFactor = len(c.nodes)
+	return c
+}
+
+// newCluster builds the cluster of a simulation, with every link configured by cfg.
+func newCluster(cfg config.Network, ids ...string) *Cluster {
+	net := NewNetwork(int64(cfg.Seed))
+	net.SetDefaultLink(Link{
+		Latency:   Normal{Mean: cfg.Latency, StdDev: cfg.Jitter},
+		Drop:      cfg.Drop,
+		Duplicate: cfg.Duplicate,
+		Reorder:   cfg.Reorder,
+	})
+	return NewCluster(net, ids...)
+}
+
+// Network returns the network the nodes talk over.
+func (c *Cluster) Network() *Network {
+	return c.net
+}
+
+// Nodes returns the nodes in the order they were created.
+func (c *Cluster) Nodes() []*Node {
+	return c.nodes
+}
+
+// Node returns the node with the given id, or nil.
+func (c *Cluster) Node(id string) *Node {
+	for _, n := range c.nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// Crash stops a node: it can no longer send or receive messages. Its timers keep firing, but what they send is lost.
+func (c *Cluster) Crash(id string) {
+	c.net.Crash(id)
+}
+
+// Restart brings a crashed node back. Everything the node stored is treated as durable,
+// but the consensus engine forgets its volatile state, such as who leads, and fails the commands it was running.
+func (c *Cluster) Restart(id string) {
+	c.net.Restart(id)
+	if n := c.Node(id); n != nil && n.consensus != nil {
+		n.consensus.restart()
+	}
+}
+
+// peers returns every node other than id.
+func (c *Cluster) peers(id string) []*Node {
+	var out []*Node
+	for _, n := range c.nodes {
+		if n.ID != id {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Replicas returns the value every node holds for key.
+func (c *Cluster) Replicas(key string) map[string]string {
+	out := make(map[string]string, len(c.nodes))
+	for _, n := range c.nodes {
+		v, _ := n.Get(key)
+		out[n.ID] = v
+	}
+	return out
+}
+
+// Diverged reports whether the nodes disagree on the value of key.
+func (c *Cluster) Diverged(key string) bool {
+	seen := make(map[string]bool)
+	for _, v := range c.Replicas(key) {
+		seen[v] = true
+	}
+	return len(seen) > 1
+}
+
+// await starts an asynchronous operation and runs the simulation until it calls done.
+func (c *Cluster) await(start func(done func(error))) error {
+	var (
+		finished bool
+		result   error
+	)
+	start(func(err error) {
+		if !finished {
+			finished, result = true, err
+		}
+	})
+	if !c.net.RunUntil(func() bool { return finished }, 2*c.Timeout) {
+		return ErrTimeout
+	}
+	return result
+}
+
+// readSync reads key on nodeID, waiting for any write to it that is still in doubt.
+func (c *Cluster) readSync(nodeID, key string) (string, error) {
+	var v string
+	err := c.await(func(done func(error)) {
+		c.Node(nodeID).readSync(key, func(value string, err error) {
+			v = value
+			done(err)
+		})
+	})
+	return v, err
+}
+
+// Run lets d of virtual time pass, returning early if ctx is cancelled.
+func (c *Cluster) Run(ctx context.Context, d time.Duration) error {
+	const slice = 10 * time.Millisecond
+	for d > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		step := min(d, slice)
+		c.net.RunFor(step)
+		d -= step
+	}
+	return ctx.Err()
+}
+
+func partition(ctx context.Context, system string, c *Cluster, groups ...[]string) {
+	c.net.Partition(groups...)
+	logging.FromContext(ctx).Debug("network partition changed", "system", system, "partitioned", true, "groups", groups)
+	events.Emit(ctx, events.PartitionChanged{System: system, Partitioned: true, Groups: groups})
+}
+
+func heal(ctx context.Context, system string, c *Cluster) {
+	c.net.Heal()
+	logging.FromContext(ctx).Debug("network partition changed", "system", system, "partitioned", false)
+	events.Emit(ctx, events.PartitionChanged{System: system, Partitioned: false})
+}
+
+func showReplicas(ctx context.Context, system string, c *Cluster, key string) {
+	events.Emit(ctx, events.ReplicaState{System: system, Key: key, Values: c.Replicas(key)})
+}
+
+func showNetwork(ctx context.Context, system string, c *Cluster) {
+	events.Emit(ctx, events.Result{Name: system + " network", Value: c.net.Stats()})
+}
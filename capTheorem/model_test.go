@@ -0,0 +1,57 @@
+package capTheorem
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+func TestSynchronousReplication(t *testing.T) {
+	ctx := events.WithSink(context.Background(), events.Discard)
+	tests := []struct {
+		name string
+		cfg  config.Network
+	}{
+		{"reliable", config.Network{Seed: 1}},
+		{"lossy", config.Network{Seed: 7, Drop: 0.3, Duplicate: 0.2, Reorder: 0.2}},
+	}
+
+	for _, tt := range tests {
+		c := newCluster(tt.cfg, "A", "B", "C")
+		if err := writeData(ctx, c, "A", "v1"); err != nil {
+			t.Fatalf("%s: write = %v", tt.name, err)
+		}
+		for node := range c.Replicas(dataKey) {
+			if v, err := c.readSync(node, dataKey); err != nil || v != "v1" {
+				t.Errorf("%s: read on %s = %q, %v; want v1", tt.name, node, v, err)
+			}
+		}
+	}
+}
+
+func TestPartitionedReplication(t *testing.T) {
+	ctx := events.WithSink(context.Background(), events.Discard)
+	c := newCluster(config.Network{Seed: 1}, "A", "B", "C")
+	c.Network().Partition([]string{"A", "B"}, []string{"C"})
+
+	// Without a failure detector nobody suspects C: the write waits for its vote and times out
+	if err := writeData(ctx, c, "A", "ca"); !errors.Is(err, ErrUnavailable) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("CA write during partition = %v; want %v and %v", err, ErrUnavailable, ErrTimeout)
+	}
+	if c.Diverged(dataKey) {
+		t.Fatalf("rejected CA write left replicas diverged: %v", c.Replicas(dataKey))
+	}
+
+	writeDataAP(ctx, c, "A", "left", nil)
+	writeDataAP(ctx, c, "C", "right", nil)
+	c.Network().RunFor(c.Timeout)
+	want := map[string]string{"A": "left", "B": "left", "C": "right"}
+	for node, v := range c.Replicas(dataKey) {
+		if v != want[node] {
+			t.Errorf("replica %s = %q; want %q", node, v, want[node])
+		}
+	}
+}
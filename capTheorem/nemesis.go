@@ -0,0 +1,136 @@
+package capTheorem
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"GoBestPratices/events"
+)
+
+//Nemesis
+//The nemesis injects one fault at a time into a cluster, then repairs it:
+//- partition: the nodes are split into two random halves
+//- crash: a node stops, then restarts with what it stored but without its volatile state
+//- skew: the clock of a node jumps ahead or behind by up to a few election timeouts
+//- delay: the links to and from a node become slow, up to the client timeout
+//Its choices come from the network's seed, like everything else in the simulation.
+
+// Faults are the kinds of faults a nemesis can inject.
+var Faults = []string{"partition", "crash", "skew", "delay"}
+
+// nemesis injects the faults of its kinds into c, one at a time.
+type nemesis struct {
+	c      *Cluster
+	system string
+	kinds  []string
+	// repair undoes the current fault, nil when there is none
+	repair func()
+	// log describes every fault and repair with the virtual time it happened
+	log []string
+}
+
+func newNemesis(c *Cluster, system string, kinds []string) (*nemesis, error) {
+	for _, k := range kinds {
+		if !slices.Contains(Faults, k) {
+			return nil, fmt.Errorf("unknown fault %q, want one of %s", k, strings.Join(Faults, ", "))
+		}
+	}
+	return &nemesis{c: c, system: system, kinds: kinds}, nil
+}
+
+// schedule alternates faults and quiet periods of length interval until stop reports true.
+func (m *nemesis) schedule(ctx context.Context, interval time.Duration, stop func() bool) {
+	if len(m.kinds) == 0 {
+		return
+	}
+	var quiet, fault func()
+	quiet = func() {
+		if stop() {
+			return
+		}
+		m.inject(ctx)
+		m.c.net.After(interval, fault)
+	}
+	fault = func() {
+		m.heal()
+		m.c.net.After(interval, quiet)
+	}
+	m.c.net.After(interval, quiet)
+}
+
+// inject starts a fault of a random kind.
+func (m *nemesis) inject(ctx context.Context) {
+	rng := m.c.net.Rand()
+	ids := make([]string, len(m.c.nodes))
+	for i, n := range m.c.nodes {
+		ids[i] = n.ID
+	}
+	victim := ids[rng.Intn(len(ids))]
+
+	switch kind := m.kinds[rng.Intn(len(m.kinds))]; kind {
+	case "partition":
+		rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		half := len(ids) / 2
+		minority, majority := slices.Sorted(slices.Values(ids[:half])), slices.Sorted(slices.Values(ids[half:]))
+		partition(ctx, m.system, m.c, minority, majority)
+		m.logFault(fmt.Sprintf("partition %v %v", minority, majority))
+		m.repair = func() {
+			heal(ctx, m.system, m.c)
+			m.logFault("heal")
+		}
+	case "crash":
+		m.c.Crash(victim)
+		m.record(ctx, "crash "+victim)
+		m.repair = func() {
+			m.c.Restart(victim)
+			m.record(ctx, "restart "+victim)
+		}
+	case "skew":
+		limit := 4 * int64(m.c.ElectionTimeout)
+		skew := time.Duration(rng.Int63n(2*limit+1) - limit).Round(time.Millisecond)
+		m.c.net.SetClockSkew(victim, skew)
+		m.record(ctx, fmt.Sprintf("skew %s by %v", victim, skew))
+		m.repair = func() {
+			m.c.net.SetClockSkew(victim, 0)
+			m.record(ctx, "reset the clock of "+victim)
+		}
+	case "delay":
+		slow := Uniform{Min: m.c.Timeout / 10, Max: m.c.Timeout}
+		for _, peer := range m.c.peers(victim) {
+			for _, link := range [][2]string{{victim, peer.ID}, {peer.ID, victim}} {
+				l := m.c.net.link(link[0], link[1])
+				l.Latency = slow
+				m.c.net.SetLink(link[0], link[1], l)
+			}
+		}
+		m.record(ctx, fmt.Sprintf("delay the links of %s by %v to %v", victim, slow.Min, slow.Max))
+		m.repair = func() {
+			for _, peer := range m.c.peers(victim) {
+				m.c.net.ResetLink(victim, peer.ID)
+				m.c.net.ResetLink(peer.ID, victim)
+			}
+			m.record(ctx, "restore the links of "+victim)
+		}
+	}
+}
+
+func (m *nemesis) logFault(what string) {
+	m.log = append(m.log, fmt.Sprintf("%v %s", m.c.net.Elapsed().Round(time.Millisecond), what))
+}
+
+// heal repairs the current fault, if any.
+func (m *nemesis) heal() {
+	if m.repair != nil {
+		m.repair()
+		m.repair = nil
+	}
+}
+
+// record logs a fault and reports it; partitions are reported by the partition and heal helpers.
+func (m *nemesis) record(ctx context.Context, what string) {
+	m.logFault(what)
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: nemesis: %s", m.system, what)})
+}
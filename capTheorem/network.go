@@ -0,0 +1,389 @@
+package capTheorem
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+//Simulated network
+//The Network delivers messages between nodes in virtual time, one event at a time, so a run is fully determined by its seed.
+//Every link has a latency distribution and may drop, duplicate or reorder messages.
+//A partition splits the nodes into groups: messages between groups are lost, including the ones already in flight.
+//A crashed node loses every message sent to or from it until it restarts, and each node may read the time with a skew.
+
+// Message is a payload travelling from one node to another.
+type Message struct {
+	From    string
+	To      string
+	Payload any
+	Sent    time.Time
+}
+
+// Handler receives the messages delivered to a node.
+type Handler func(Message)
+
+// Latency is a distribution of one-way message delays.
+type Latency interface {
+	Sample(r *rand.Rand) time.Duration
+}
+
+// Fixed delays every message by the same amount.
+type Fixed time.Duration
+
+func (f Fixed) Sample(*rand.Rand) time.Duration { return time.Duration(f) }
+
+// Uniform delays messages by a duration picked evenly in [Min, Max].
+type Uniform struct {
+	Min, Max time.Duration
+}
+
+func (u Uniform) Sample(r *rand.Rand) time.Duration {
+	if u.Max <= u.Min {
+		return u.Min
+	}
+	return u.Min + time.Duration(r.Int63n(int64(u.Max-u.Min)+1))
+}
+
+// Normal delays messages around Mean, never less than zero.
+type Normal struct {
+	Mean, StdDev time.Duration
+}
+
+func (n Normal) Sample(r *rand.Rand) time.Duration {
+	d := n.Mean + time.Duration(r.NormFloat64()*float64(n.StdDev))
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Exponential delays most messages a little and a few a lot.
+type Exponential struct {
+	Mean time.Duration
+}
+
+func (e Exponential) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(e.Mean))
+}
+
+// Link describes how one direction of a connection behaves.
+// Drop, Duplicate and Reorder are probabilities between 0 and 1.
+type Link struct {
+	Latency   Latency
+	Drop      float64
+	Duplicate float64
+	Reorder   float64
+}
+
+// NetworkStats counts what happened to the messages sent so far.
+type NetworkStats struct {
+	Sent        int `json:"sent"`
+	Delivered   int `json:"delivered"`
+	Dropped     int `json:"dropped"`
+	Duplicated  int `json:"duplicated"`
+	Reordered   int `json:"reordered"`
+	Partitioned int `json:"partitioned"`
+	Crashed     int `json:"crashed"`
+}
+
+func (s NetworkStats) String() string {
+	out := fmt.Sprintf("sent=%d delivered=%d dropped=%d duplicated=%d reordered=%d lost to partition=%d",
+		s.Sent, s.Delivered, s.Dropped, s.Duplicated, s.Reordered, s.Partitioned)
+	if s.Crashed > 0 {
+		out += fmt.Sprintf(" lost to crash=%d", s.Crashed)
+	}
+	return out
+}
+
+// Network is a deterministic discrete-event simulation of the links between nodes.
+// It is not safe for concurrent use: handlers and timers run on the goroutine that drives it.
+type Network struct {
+	rng      *rand.Rand
+	start    time.Time
+	now      time.Time
+	seq      uint64
+	queue    eventQueue
+	handlers map[string]Handler
+	group    map[string]int
+	links    map[[2]string]Link
+	fallback Link
+	fifo     map[[2]string]time.Time
+	down     map[string]bool
+	skew     map[string]time.Duration
+	stats    NetworkStats
+}
+
+// NewNetwork returns a network with instant, reliable links whose randomness comes from seed.
+func NewNetwork(seed int64) *Network {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &Network{
+		rng:      rand.New(rand.NewSource(seed)),
+		start:    start,
+		now:      start,
+		handlers: make(map[string]Handler),
+		links:    make(map[[2]string]Link),
+		fallback: Link{Latency: Fixed(0)},
+		fifo:     make(map[[2]string]time.Time),
+		down:     make(map[string]bool),
+		skew:     make(map[string]time.Duration),
+	}
+}
+
+// Register delivers the messages sent to id to h.
+func (n *Network) Register(id string, h Handler) {
+	n.handlers[id] = h
+}
+
+// SetDefaultLink sets the behaviour of every link without its own settings.
+func (n *Network) SetDefaultLink(l Link) {
+	n.fallback = l
+}
+
+// SetLink sets the behaviour of messages sent from one node to another.
+func (n *Network) SetLink(from, to string, l Link) {
+	n.links[[2]string{from, to}] = l
+}
+
+// ResetLink makes messages sent from one node to another follow the default link again.
+func (n *Network) ResetLink(from, to string) {
+	delete(n.links, [2]string{from, to})
+}
+
+// Partition splits the nodes into groups that cannot reach each other.
+// Nodes not listed in any group are isolated from everyone.
+func (n *Network) Partition(groups ...[]string) {
+	n.group = make(map[string]int)
+	for i, g := range groups {
+		for _, id := range g {
+			n.group[id] = i + 1
+		}
+	}
+}
+
+// Heal reconnects every node.
+func (n *Network) Heal() {
+	n.group = nil
+}
+
+// Partitioned reports whether a partition is in place.
+func (n *Network) Partitioned() bool {
+	return n.group != nil
+}
+
+// Crash stops a node: messages sent to or from it are lost, including the ones in flight, until it restarts.
+func (n *Network) Crash(id string) {
+	n.down[id] = true
+}
+
+// Restart brings a crashed node back.
+func (n *Network) Restart(id string) {
+	delete(n.down, id)
+}
+
+// Crashed reports whether a node is down.
+func (n *Network) Crashed(id string) bool {
+	return n.down[id]
+}
+
+// Reachable reports whether messages from a can currently reach b.
+func (n *Network) Reachable(a, b string) bool {
+	if n.down[a] || n.down[b] {
+		return false
+	}
+	if a == b || n.group == nil {
+		return true
+	}
+	ga, gb := n.group[a], n.group[b]
+	return ga != 0 && ga == gb
+}
+
+// Rand returns the seeded source shared by the simulation.
+func (n *Network) Rand() *rand.Rand {
+	return n.rng
+}
+
+// Now returns the virtual time.
+func (n *Network) Now() time.Time {
+	return n.now
+}
+
+// SetClockSkew makes the clock of a node run d ahead of the virtual time, or behind it if d is negative.
+func (n *Network) SetClockSkew(id string, d time.Duration) {
+	if d == 0 {
+		delete(n.skew, id)
+		return
+	}
+	n.skew[id] = d
+}
+
+// Clock returns the time as read by a node, with its skew.
+func (n *Network) Clock(id string) time.Time {
+	return n.now.Add(n.skew[id])
+}
+
+// Elapsed returns the virtual time since the network was created.
+func (n *Network) Elapsed() time.Duration {
+	return n.now.Sub(n.start)
+}
+
+// Stats returns the message counters.
+func (n *Network) Stats() NetworkStats {
+	return n.stats
+}
+
+// Send schedules the delivery of payload according to the link from -> to.
+// Links are FIFO unless a message is picked for reordering, which lets later messages overtake it.
+func (n *Network) Send(from, to string, payload any) {
+	n.stats.Sent++
+	if n.down[from] || n.down[to] {
+		n.stats.Crashed++
+		return
+	}
+	if !n.Reachable(from, to) {
+		n.stats.Partitioned++
+		return
+	}
+	l := n.link(from, to)
+	if n.rng.Float64() < l.Drop {
+		n.stats.Dropped++
+		return
+	}
+	copies := 1
+	if n.rng.Float64() < l.Duplicate {
+		n.stats.Duplicated++
+		copies++
+	}
+	msg := Message{From: from, To: to, Payload: payload, Sent: n.now}
+	for i := 0; i < copies; i++ {
+		at := n.now.Add(l.Latency.Sample(n.rng))
+		key := [2]string{from, to}
+		if n.rng.Float64() < l.Reorder {
+			n.stats.Reordered++
+			at = at.Add(l.Latency.Sample(n.rng))
+		} else {
+			if last := n.fifo[key]; at.Before(last) {
+				at = last
+			}
+			n.fifo[key] = at
+		}
+		n.schedule(at, func() { n.deliver(msg) })
+	}
+}
+
+func (n *Network) deliver(msg Message) {
+	// Messages in flight are lost if a crash or a partition started after they were sent
+	if n.down[msg.From] || n.down[msg.To] {
+		n.stats.Crashed++
+		return
+	}
+	if !n.Reachable(msg.From, msg.To) {
+		n.stats.Partitioned++
+		return
+	}
+	h, ok := n.handlers[msg.To]
+	if !ok {
+		n.stats.Dropped++
+		return
+	}
+	n.stats.Delivered++
+	h(msg)
+}
+
+func (n *Network) link(from, to string) Link {
+	l, ok := n.links[[2]string{from, to}]
+	if !ok {
+		l = n.fallback
+	}
+	if l.Latency == nil {
+		l.Latency = Fixed(0)
+	}
+	return l
+}
+
+// Timer is a callback scheduled in virtual time.
+type Timer struct {
+	stopped bool
+}
+
+// Stop prevents the callback from running if it has not run yet.
+func (t *Timer) Stop() {
+	t.stopped = true
+}
+
+// After runs fn once d of virtual time has passed.
+func (n *Network) After(d time.Duration, fn func()) *Timer {
+	t := &Timer{}
+	n.schedule(n.now.Add(d), func() {
+		if !t.stopped {
+			fn()
+		}
+	})
+	return t
+}
+
+func (n *Network) schedule(at time.Time, fn func()) {
+	n.seq++
+	heap.Push(&n.queue, &simEvent{at: at, seq: n.seq, fn: fn})
+}
+
+// Step runs the next scheduled event and reports whether there was one.
+func (n *Network) Step() bool {
+	if n.queue.Len() == 0 {
+		return false
+	}
+	ev := heap.Pop(&n.queue).(*simEvent)
+	if ev.at.After(n.now) {
+		n.now = ev.at
+	}
+	ev.fn()
+	return true
+}
+
+// RunFor runs every event due in the next d of virtual time.
+func (n *Network) RunFor(d time.Duration) {
+	end := n.now.Add(d)
+	for n.queue.Len() > 0 && !n.queue[0].at.After(end) {
+		n.Step()
+	}
+	n.now = end
+}
+
+// RunUntil runs events until done returns true or limit of virtual time has passed.
+func (n *Network) RunUntil(done func() bool, limit time.Duration) bool {
+	end := n.now.Add(limit)
+	for !done() {
+		if n.queue.Len() == 0 || n.queue[0].at.After(end) {
+			n.now = end
+			return done()
+		}
+		n.Step()
+	}
+	return true
+}
+
+type simEvent struct {
+	at  time.Time
+	seq uint64
+	fn  func()
+}
+
+// eventQueue orders events by time, then by the order they were scheduled
+type eventQueue []*simEvent
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x any)   { *q = append(*q, x.(*simEvent)) }
+func (q *eventQueue) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,151 @@
+package capTheorem
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNetworkPartition(t *testing.T) {
+	net := NewNetwork(1)
+	net.Partition([]string{"A", "B"}, []string{"C"})
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"A", "B", true},
+		{"A", "C", false},
+		{"C", "B", false},
+		{"C", "C", true},
+		{"A", "D", false}, // not in any group
+	}
+	for _, tt := range tests {
+		if got := net.Reachable(tt.a, tt.b); got != tt.want {
+			t.Errorf("Reachable(%s, %s) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	net.Heal()
+	if net.Partitioned() || !net.Reachable("A", "C") {
+		t.Error("nodes still partitioned after Heal")
+	}
+}
+
+func TestNetworkLosesMessagesInFlight(t *testing.T) {
+	net := NewNetwork(1)
+	net.SetDefaultLink(Link{Latency: Fixed(10 * time.Millisecond)})
+	var got []any
+	net.Register("B", func(m Message) { got = append(got, m.Payload) })
+
+	net.Send("A", "B", 1)
+	net.RunFor(5 * time.Millisecond)
+	net.Partition([]string{"A"}, []string{"B"})
+	net.RunFor(time.Second)
+
+	if len(got) != 0 {
+		t.Errorf("delivered %v across a partition", got)
+	}
+	if s := net.Stats(); s.Partitioned != 1 {
+		t.Errorf("Stats().Partitioned = %d; want 1", s.Partitioned)
+	}
+}
+
+func TestNetworkCrash(t *testing.T) {
+	net := NewNetwork(1)
+	net.SetDefaultLink(Link{Latency: Fixed(10 * time.Millisecond)})
+	var got []any
+	net.Register("A", func(m Message) { got = append(got, m.Payload) })
+	net.Register("B", func(m Message) { got = append(got, m.Payload) })
+
+	net.Send("A", "B", 1)
+	net.RunFor(5 * time.Millisecond)
+	net.Crash("B")
+	net.Send("A", "B", 2)
+	net.Send("B", "A", 3)
+	net.RunFor(time.Second)
+	if len(got) != 0 || net.Reachable("A", "B") {
+		t.Fatalf("crashed node exchanged %v", got)
+	}
+	if s := net.Stats(); s.Crashed != 3 {
+		t.Errorf("Stats().Crashed = %d; want 3", s.Crashed)
+	}
+
+	net.Restart("B")
+	net.Send("A", "B", 4)
+	net.RunFor(time.Second)
+	if !reflect.DeepEqual(got, []any{4}) {
+		t.Errorf("restarted node received %v; want [4]", got)
+	}
+}
+
+func TestNetworkClockSkew(t *testing.T) {
+	net := NewNetwork(1)
+	net.SetClockSkew("A", -time.Second)
+	net.RunFor(time.Minute)
+	if got := net.Clock("A").Sub(net.Clock("B")); got != -time.Second {
+		t.Errorf("A's clock is %v from B's; want -1s", got)
+	}
+	net.SetClockSkew("A", 0)
+	if !net.Clock("A").Equal(net.Now()) {
+		t.Error("clock still skewed after resetting it")
+	}
+}
+
+// deliveries sends 0..n-1 from A to B and returns the order B received them in.
+func deliveries(seed int64, l Link, n int) []int {
+	net := NewNetwork(seed)
+	net.SetDefaultLink(l)
+	var got []int
+	net.Register("B", func(m Message) { got = append(got, m.Payload.(int)) })
+	for i := 0; i < n; i++ {
+		net.Send("A", "B", i)
+		net.RunFor(time.Millisecond)
+	}
+	net.RunFor(time.Second)
+	return got
+}
+
+func TestNetworkLinks(t *testing.T) {
+	jittery := Normal{Mean: 20 * time.Millisecond, StdDev: 15 * time.Millisecond}
+
+	tests := []struct {
+		name string
+		link Link
+		// check inspects the deliveries of 100 messages
+		check func([]int) bool
+	}{
+		{"fifo despite jitter", Link{Latency: jittery}, func(got []int) bool {
+			for i, v := range got {
+				if v != i {
+					return false
+				}
+			}
+			return len(got) == 100
+		}},
+		{"reorder", Link{Latency: jittery, Reorder: 0.5}, func(got []int) bool {
+			for i, v := range got {
+				if v != i {
+					return len(got) == 100
+				}
+			}
+			return false
+		}},
+		{"drop", Link{Latency: jittery, Drop: 0.3}, func(got []int) bool {
+			return len(got) > 50 && len(got) < 90
+		}},
+		{"duplicate", Link{Latency: jittery, Duplicate: 0.3}, func(got []int) bool {
+			return len(got) > 110 && len(got) < 150
+		}},
+	}
+
+	for _, tt := range tests {
+		got := deliveries(42, tt.link, 100)
+		if !tt.check(got) {
+			t.Errorf("%s: unexpected deliveries %v", tt.name, got)
+		}
+		if again := deliveries(42, tt.link, 100); !reflect.DeepEqual(got, again) {
+			t.Errorf("%s: same seed delivered %v, then %v", tt.name, got, again)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package capTheorem
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+//Multi-Paxos
+//Every log slot is decided by its own Paxos instance, but a distinguished proposer (the leader) runs phase 1 once for all of them.
+//Phase 1 (prepare/promise): a proposer picks a ballot higher than any it has seen and asks the acceptors to promise to ignore lower ones;
+//with a majority of promises it becomes leader, and must re-propose the highest-ballot value any of them accepted in each open slot.
+//Phase 2 (accept/accepted): the leader proposes each new command in the next slot; a majority of acceptances chooses it,
+//and the leader tells the learners. A lagging learner asks the leader for the values it missed.
+//Like Raft, a node compacts its applied slots into a snapshot every SnapshotThreshold slots, and forgets the values it accepted for them.
+//A learner lagging behind the leader's snapshot receives the snapshot instead of the slots, and so does a proposer whose
+//prepare starts before an acceptor's snapshot: the acceptor may have forgotten the value it accepted for a chosen slot.
+//A node that hears no leader for an election timeout starts phase 1 with a higher ballot, which preempts the previous leader.
+
+// Ballot orders the proposals of different proposers: by number, then by node.
+type Ballot struct {
+	N    uint64
+	Node string
+}
+
+func (b Ballot) less(o Ballot) bool {
+	if b.N != o.N {
+		return b.N < o.N
+	}
+	return b.Node < o.Node
+}
+
+func (b Ballot) String() string {
+	return fmt.Sprintf("%d.%s", b.N, b.Node)
+}
+
+// paxosSlot is a value of a log slot, accepted in Ballot.
+type paxosSlot struct {
+	Slot   uint64
+	Ballot Ballot
+	Cmd    Command
+}
+
+// paxosPrepare asks the acceptors to promise Ballot for every slot from From on.
+type paxosPrepare struct {
+	Ballot Ballot
+	From   uint64
+}
+
+// paxosPromise grants a prepare with the values accepted so far, or refuses it because of a higher promise.
+// Snapshot is set when the acceptor compacted slots the proposer has not applied.
+type paxosPromise struct {
+	Ballot   Ballot
+	OK       bool
+	Promised Ballot
+	Accepted []paxosSlot
+	Snapshot *Snapshot
+}
+
+type paxosAccept struct {
+	Ballot Ballot
+	Slot   uint64
+	Cmd    Command
+}
+
+type paxosAccepted struct {
+	Ballot   Ballot
+	Slot     uint64
+	OK       bool
+	Promised Ballot
+}
+
+// paxosLearn tells a learner the values chosen for some slots.
+type paxosLearn struct {
+	Chosen []paxosSlot
+}
+
+// paxosHeartbeat tells the nodes the leader is alive and how many slots it has applied.
+type paxosHeartbeat struct {
+	Ballot  Ballot
+	Applied uint64
+}
+
+// paxosLag asks the leader for the chosen values after Applied.
+type paxosLag struct {
+	Applied uint64
+}
+
+// paxosSnapshot replaces the slots a learner missed and the leader compacted.
+type paxosSnapshot struct {
+	Snapshot Snapshot
+}
+
+type paxosProposal struct {
+	cmd     Command
+	accepts map[string]bool
+}
+
+// paxos is the Multi-Paxos state of one node, which is proposer, acceptor and learner.
+type paxos struct {
+	node    *Node
+	pending pendingCommands
+
+	// Acceptor
+	promised Ballot
+	accepted map[uint64]paxosSlot
+
+	// Learner: log holds the commands applied after the snapshot, chosen the ones waiting for an earlier slot
+	log       []Command
+	chosen    map[uint64]Command
+	snapshot  Snapshot
+	snapIndex uint64
+	installed int
+
+	// Proposer
+	ballot      Ballot
+	preparing   bool
+	leading     bool
+	promises    map[string]bool
+	recovered   map[uint64]paxosSlot
+	nextSlot    uint64
+	proposals   map[uint64]*paxosProposal
+	appended    map[CommandID]bool
+	leader      string
+	lastContact time.Time
+	election    *Timer
+}
+
+// StartPaxos runs Multi-Paxos on every node of the cluster.
+func (c *Cluster) StartPaxos() {
+	for _, n := range c.nodes {
+		p := &paxos{
+			node:     n,
+			pending:  make(pendingCommands),
+			accepted: make(map[uint64]paxosSlot),
+			chosen:   make(map[uint64]Command),
+		}
+		n.consensus = p
+		p.resetElectionTimer()
+	}
+}
+
+func (p *paxos) Leader() (string, uint64) {
+	if p.leading {
+		return p.node.ID, p.ballot.N
+	}
+	return p.leader, p.promised.N
+}
+
+func (p *paxos) applied() uint64 {
+	return p.snapIndex + uint64(len(p.log))
+}
+
+func (p *paxos) majority() int {
+	return len(p.node.cluster.nodes)/2 + 1
+}
+
+// broadcast sends payload to every node, this one included.
+func (p *paxos) broadcast(payload any) {
+	for _, n := range p.node.cluster.nodes {
+		p.node.send(n.ID, payload)
+	}
+}
+
+func (p *paxos) Propose(cmd Command, done func(string, error)) {
+	p.submit(p.pending.add(p.node, cmd, done, func() string { return p.leader }))
+}
+
+// submit proposes cmd if the node leads, or forwards it to the leader, until the command completes.
+func (p *paxos) submit(cmd Command) {
+	if !p.pending.waiting(cmd.ID) {
+		return
+	}
+	switch {
+	case p.leading:
+		p.appendCommand(cmd)
+	case p.leader != "" && p.leader != p.node.ID:
+		p.node.send(p.leader, forwardCommand{Cmd: cmd})
+	}
+	p.node.cluster.net.After(p.node.cluster.Retry, func() { p.submit(cmd) })
+}
+
+func (p *paxos) appendCommand(cmd Command) {
+	if p.node.applied(cmd.ID) || p.appended[cmd.ID] {
+		return
+	}
+	p.nextSlot++
+	p.propose(p.nextSlot, cmd)
+}
+
+func (p *paxos) receive(from string, payload any) {
+	switch m := payload.(type) {
+	case paxosPrepare:
+		p.onPrepare(from, m)
+	case paxosPromise:
+		p.onPromise(from, m)
+	case paxosAccept:
+		p.onAccept(from, m)
+	case paxosAccepted:
+		p.onAccepted(from, m)
+	case paxosLearn:
+		for _, s := range m.Chosen {
+			p.learn(s.Slot, s.Cmd)
+		}
+	case paxosHeartbeat:
+		p.onHeartbeat(from, m)
+	case paxosLag:
+		p.onLag(from, m)
+	case paxosSnapshot:
+		p.install(m.Snapshot)
+	case forwardCommand:
+		if p.leading {
+			p.appendCommand(m.Cmd)
+		}
+	}
+}
+
+// restart forgets who leads and the proposals in progress, and fails the commands submitted on the node.
+// The promised ballot, the accepted values, the snapshot and the applied log are durable.
+func (p *paxos) restart() {
+	p.pending.fail(ErrCrashed)
+	p.preparing, p.leading = false, false
+	p.leader = ""
+	p.promises, p.recovered, p.proposals = nil, nil, nil
+	p.chosen = make(map[uint64]Command)
+	p.resetElectionTimer()
+}
+
+// Phase 1
+
+func (p *paxos) resetElectionTimer() {
+	if p.election != nil {
+		p.election.Stop()
+	}
+	c := p.node.cluster
+	d := c.ElectionTimeout + time.Duration(c.net.Rand().Int63n(int64(c.ElectionTimeout)))
+	p.election = c.net.After(d, p.prepare)
+}
+
+func (p *paxos) prepare() {
+	p.ballot = Ballot{N: max(p.promised.N, p.ballot.N) + 1, Node: p.node.ID}
+	p.preparing, p.leading = true, false
+	p.leader = ""
+	p.promises = make(map[string]bool)
+	p.recovered = make(map[uint64]paxosSlot)
+	p.resetElectionTimer()
+	p.broadcast(paxosPrepare{Ballot: p.ballot, From: p.applied() + 1})
+}
+
+// heard records a message of the proposer of ballot b, which the node follows from now on.
+func (p *paxos) heard(b Ballot) {
+	p.promised = b
+	if b == p.ballot {
+		return
+	}
+	p.preparing, p.leading = false, false
+	p.leader = b.Node
+	p.lastContact = p.node.now()
+	p.resetElectionTimer()
+}
+
+func (p *paxos) onPrepare(from string, m paxosPrepare) {
+	if m.Ballot.less(p.promised) {
+		p.node.send(from, paxosPromise{Ballot: m.Ballot, Promised: p.promised})
+		return
+	}
+	p.heard(m.Ballot)
+	var accepted []paxosSlot
+	for slot, s := range p.accepted {
+		if slot >= m.From {
+			accepted = append(accepted, s)
+		}
+	}
+	sort.Slice(accepted, func(i, j int) bool { return accepted[i].Slot < accepted[j].Slot })
+	promise := paxosPromise{Ballot: m.Ballot, OK: true, Accepted: accepted}
+	if m.From <= p.snapIndex {
+		snap := p.snapshot
+		promise.Snapshot = &snap
+	}
+	p.node.send(from, promise)
+}
+
+func (p *paxos) onPromise(from string, m paxosPromise) {
+	if !p.preparing || m.Ballot != p.ballot {
+		return
+	}
+	if !m.OK {
+		// Preempted: the next attempt will use a higher ballot
+		p.promised = maxBallot(p.promised, m.Promised)
+		p.preparing = false
+		return
+	}
+	p.promises[from] = true
+	if m.Snapshot != nil {
+		p.install(*m.Snapshot)
+	}
+	for _, s := range m.Accepted {
+		if cur, ok := p.recovered[s.Slot]; !ok || cur.Ballot.less(s.Ballot) {
+			p.recovered[s.Slot] = s
+		}
+	}
+	if len(p.promises) >= p.majority() {
+		p.lead()
+	}
+}
+
+// lead finishes phase 1: values accepted in open slots are proposed again, and gaps are filled with no-ops.
+func (p *paxos) lead() {
+	p.preparing, p.leading = false, true
+	p.leader = p.node.ID
+	p.election.Stop()
+	p.proposals = make(map[uint64]*paxosProposal)
+	p.appended = make(map[CommandID]bool)
+	p.nextSlot = p.applied()
+	for slot := range p.recovered {
+		p.nextSlot = max(p.nextSlot, slot)
+	}
+	for slot := p.applied() + 1; slot <= p.nextSlot; slot++ {
+		cmd, ok := p.chosen[slot]
+		if !ok {
+			cmd = p.recovered[slot].Cmd
+		}
+		p.propose(slot, cmd)
+	}
+
+	ballot := p.ballot
+	var beat func()
+	beat = func() {
+		if !p.leading || p.ballot != ballot {
+			return
+		}
+		p.broadcast(paxosHeartbeat{Ballot: ballot, Applied: p.applied()})
+		// Ask again for the slots that are not chosen yet
+		slots := make([]uint64, 0, len(p.proposals))
+		for slot := range p.proposals {
+			slots = append(slots, slot)
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+		for _, slot := range slots {
+			p.broadcast(paxosAccept{Ballot: ballot, Slot: slot, Cmd: p.proposals[slot].cmd})
+		}
+		p.node.cluster.net.After(p.node.cluster.Heartbeat, beat)
+	}
+	beat()
+}
+
+// stepDown stops leading after an acceptor promised a higher ballot.
+func (p *paxos) stepDown(promised Ballot) {
+	p.promised = maxBallot(p.promised, promised)
+	p.leading, p.preparing = false, false
+	p.leader = ""
+	p.resetElectionTimer()
+}
+
+// Phase 2
+
+func (p *paxos) propose(slot uint64, cmd Command) {
+	p.proposals[slot] = &paxosProposal{cmd: cmd, accepts: make(map[string]bool)}
+	p.appended[cmd.ID] = true
+	p.broadcast(paxosAccept{Ballot: p.ballot, Slot: slot, Cmd: cmd})
+}
+
+func (p *paxos) onAccept(from string, m paxosAccept) {
+	if m.Ballot.less(p.promised) {
+		p.node.send(from, paxosAccepted{Ballot: m.Ballot, Slot: m.Slot, Promised: p.promised})
+		return
+	}
+	p.heard(m.Ballot)
+	p.accepted[m.Slot] = paxosSlot{Slot: m.Slot, Ballot: m.Ballot, Cmd: m.Cmd}
+	p.node.send(from, paxosAccepted{Ballot: m.Ballot, Slot: m.Slot, OK: true})
+}
+
+func (p *paxos) onAccepted(from string, m paxosAccepted) {
+	if !m.OK {
+		if p.leading && p.ballot.less(m.Promised) {
+			p.stepDown(m.Promised)
+		}
+		return
+	}
+	prop, ok := p.proposals[m.Slot]
+	if !p.leading || m.Ballot != p.ballot || !ok {
+		return
+	}
+	prop.accepts[from] = true
+	if len(prop.accepts) >= p.majority() {
+		delete(p.proposals, m.Slot)
+		p.learn(m.Slot, prop.cmd)
+		for _, peer := range p.node.cluster.peers(p.node.ID) {
+			p.node.send(peer.ID, paxosLearn{Chosen: []paxosSlot{{Slot: m.Slot, Ballot: p.ballot, Cmd: prop.cmd}}})
+		}
+	}
+}
+
+// Learning
+
+// learn records the value chosen for slot and applies every slot that is now complete.
+func (p *paxos) learn(slot uint64, cmd Command) {
+	if slot <= p.applied() {
+		return
+	}
+	p.chosen[slot] = cmd
+	p.applyChosen()
+}
+
+// applyChosen applies the chosen slots that follow the applied ones.
+func (p *paxos) applyChosen() {
+	for {
+		next, ok := p.chosen[p.applied()+1]
+		if !ok {
+			return
+		}
+		delete(p.chosen, p.applied()+1)
+		p.log = append(p.log, next)
+		result, err := p.node.applyCommand(next)
+		p.pending.complete(next.ID, result, err)
+		if len(p.log) >= p.node.cluster.SnapshotThreshold {
+			p.compact()
+		}
+	}
+}
+
+// compact replaces the applied slots with a snapshot of the state machine, and forgets the values accepted for them.
+func (p *paxos) compact() {
+	p.snapIndex = p.applied()
+	p.snapshot = p.node.snapshot(p.snapIndex, 0)
+	p.log = nil
+	for slot := range p.accepted {
+		if slot <= p.snapIndex {
+			delete(p.accepted, slot)
+		}
+	}
+}
+
+// install applies a snapshot of slots the node has not applied yet, and the chosen slots that follow it.
+func (p *paxos) install(snap Snapshot) {
+	if snap.Index <= p.applied() {
+		return
+	}
+	p.snapIndex, p.snapshot, p.log = snap.Index, snap, nil
+	p.installed++
+	p.node.restore(snap)
+	p.pending.completeRestored(p.node)
+	for slot := range p.chosen {
+		if slot <= snap.Index {
+			delete(p.chosen, slot)
+		}
+	}
+	p.applyChosen()
+}
+
+func (p *paxos) onHeartbeat(from string, m paxosHeartbeat) {
+	if m.Ballot.less(p.promised) {
+		// Tell a deposed leader about the newer ballot
+		p.node.send(from, paxosAccepted{Ballot: m.Ballot, Promised: p.promised})
+		return
+	}
+	p.heard(m.Ballot)
+	if p.applied() < m.Applied {
+		p.node.send(from, paxosLag{Applied: p.applied()})
+	}
+}
+
+func (p *paxos) onLag(from string, m paxosLag) {
+	if !p.leading || m.Applied >= p.applied() {
+		return
+	}
+	if m.Applied < p.snapIndex {
+		p.node.send(from, paxosSnapshot{Snapshot: p.snapshot})
+		return
+	}
+	end := min(m.Applied+maxAppend, p.applied())
+	chosen := make([]paxosSlot, 0, end-m.Applied)
+	for slot := m.Applied + 1; slot <= end; slot++ {
+		chosen = append(chosen, paxosSlot{Slot: slot, Ballot: p.ballot, Cmd: p.log[slot-p.snapIndex-1]})
+	}
+	p.node.send(from, paxosLearn{Chosen: chosen})
+}
+
+func maxBallot(a, b Ballot) Ballot {
+	if a.less(b) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,58 @@
+package capTheorem
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+)
+
+func TestPaxosSnapshotCatchUp(t *testing.T) {
+	c := newCluster(config.Network{Seed: 5, Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond}, "A", "B", "C")
+	c.SnapshotThreshold = 200
+	c.StartPaxos()
+	if !c.Network().RunUntil(func() bool { id, _ := c.Leader(); return id != "" }, time.Second) {
+		t.Fatal("no leader elected")
+	}
+	leader, _ := c.Leader()
+	var lagging string
+	var rest []string
+	for _, n := range c.Nodes() {
+		if n.ID != leader && lagging == "" {
+			lagging = n.ID
+		} else {
+			rest = append(rest, n.ID)
+		}
+	}
+	c.Network().Partition(rest, []string{lagging})
+
+	const writes = 3000
+	for i := 0; i < writes; i++ {
+		cmd := Command{Op: "put", Key: catalogKey(i % 50), Value: fmt.Sprint(i)}
+		if _, err := c.propose(leader, cmd); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	lead := c.Node(leader).consensus.(*paxos)
+	if len(lead.log) >= c.SnapshotThreshold || len(lead.accepted) > c.SnapshotThreshold {
+		t.Errorf("leader keeps %d slots and %d accepted values; want fewer than %d after compaction",
+			len(lead.log), len(lead.accepted), c.SnapshotThreshold)
+	}
+
+	c.Network().Heal()
+	follower := c.Node(lagging).consensus.(*paxos)
+	if !c.Network().RunUntil(func() bool { return follower.applied() >= lead.applied() }, 5*time.Second) {
+		t.Fatalf("follower applied %d of %d slots", follower.applied(), lead.applied())
+	}
+	if follower.installed == 0 {
+		t.Error("follower caught up without a snapshot")
+	}
+	for i := 0; i < 50; i++ {
+		want, _ := c.Node(leader).Get(catalogKey(i))
+		if got, _ := c.Node(lagging).Get(catalogKey(i)); got != want {
+			t.Errorf("%s on the follower = %q; want %q", catalogKey(i), got, want)
+		}
+	}
+	checkLogs(t, c)
+}
@@ -0,0 +1,282 @@
+package capTheorem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"GoBestPratices/capTheorem/linearizability"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+//Quorum replication (Dynamo style)
+//Each key lives on N replicas picked from a hash ring, and every client operation chooses how many of them must answer:
+//a write waits for W acknowledgements and a read for R replies, returning the latest versions it saw.
+//When R+W>N every read quorum overlaps the quorum of every completed write, so a read does not miss a write that completed before it,
+//but only where enough replicas are reachable. That overlap is not linearizability: concurrent writes become siblings
+//a register cannot return, a write that missed its quorum shows up in some reads and not in later ones,
+//and a read does not wait for what it returned to reach a write quorum, so a later read may return an older value.
+//With W=1 or R=1 operations stay available on any side of a partition, at the price of stale reads.
+//CP and AP are two points on the same dial.
+//A write that misses its quorum is not rolled back: the replicas that stored it keep it, as in Dynamo.
+
+const systemQuorum = "SimulateQuorumReplication"
+
+// ErrInvalidQuorum is returned when R or W is not between 1 and N.
+var ErrInvalidQuorum = errors.New("invalid quorum")
+
+type quorumPut struct {
+	Req     uint64
+	Key     string
+	Version Version
+}
+
+type quorumGet struct {
+	Req uint64
+	Key string
+}
+
+// quorumReply acknowledges a put, or answers a get with the replica's versions.
+// For is set when a fallback node acknowledges a put on behalf of an unreachable replica.
+type quorumReply struct {
+	Req      uint64
+	Versions []Version
+	For      string
+}
+
+// quorumOp is the coordinator's view of a quorum read or write.
+type quorumOp struct {
+	need     int
+	replicas []string
+	payload  any
+	replies  map[string]bool
+	versions []Version
+	// seen holds the versions each replica answered a get with, to find the stale ones
+	seen     map[string][]Version
+	finished bool
+	done     func(*quorumOp, error)
+}
+
+// PreferenceList returns the n nodes storing key: the first n clockwise from the key on a hash ring of the nodes.
+func (c *Cluster) PreferenceList(key string, n int) []string {
+	ring := make([]string, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		ring = append(ring, node.ID)
+	}
+	sort.Slice(ring, func(i, j int) bool { return ringHash(ring[i]) < ringHash(ring[j]) })
+
+	h := ringHash(key)
+	start := sort.Search(len(ring), func(i int) bool { return ringHash(ring[i]) >= h })
+	out := make([]string, 0, n)
+	for i := 0; i < n && i < len(ring); i++ {
+		out = append(out, ring[(start+i)%len(ring)])
+	}
+	return out
+}
+
+func ringHash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// putQuorum writes key on its replicas and calls done once w of them stored it.
+// seen is the clock of the versions the client read before writing, nil for a blind write.
+func (n *Node) putQuorum(key, value string, seen VectorClock, w int, done func(error)) {
+	n.putVersion(key, n.newVersion(key, value, seen), w, done)
+}
+
+// putVersion writes a version this node created on the replicas of key and calls done once w of them stored it.
+func (n *Node) putVersion(key string, v Version, w int, done func(error)) {
+	value := v.Value
+	record := n.cluster.invoke(n.ID, linearizability.KVInput{Op: "put", Key: key, Value: value})
+	n.quorum(key, w,
+		func(req uint64) any { return quorumPut{Req: req, Key: key, Version: v} },
+		func(_ *quorumOp, err error) {
+			record(value, err)
+			done(err)
+		})
+}
+
+// getQuorum reads key from its replicas and calls done with the versions of the first r replies,
+// minus the ones superseded by others.
+func (n *Node) getQuorum(key string, r int, done func([]Version, error)) {
+	record := n.cluster.invoke(n.ID, linearizability.KVInput{Op: "get", Key: key})
+	n.quorum(key, r,
+		func(req uint64) any { return quorumGet{Req: req, Key: key} },
+		func(op *quorumOp, err error) {
+			record(strings.Join(siblingValues(op.versions), " | "), err)
+			if err != nil {
+				done(nil, err)
+				return
+			}
+			done(op.versions, nil)
+		})
+}
+
+func (n *Node) quorum(key string, need int, payload func(req uint64) any, done func(*quorumOp, error)) {
+	replicas := n.cluster.PreferenceList(key, n.cluster.ReplicationFactor)
+	if need < 1 || need > len(replicas) {
+		done(&quorumOp{}, notApplied{fmt.Errorf("%w: %d out of N=%d", ErrInvalidQuorum, need, len(replicas))})
+		return
+	}
+	n.seq++
+	req := n.seq
+	op := &quorumOp{
+		need:     need,
+		replicas: replicas,
+		payload:  payload(req),
+		replies:  make(map[string]bool),
+		seen:     make(map[string][]Version),
+		done:     done,
+	}
+	n.quorums[req] = op
+	n.cluster.net.After(n.cluster.Timeout, func() {
+		n.finishQuorum(req, fmt.Errorf("%d of %d replicas answered: %w", len(op.replies), op.need, ErrUnavailable))
+	})
+	n.sendQuorum(op)
+}
+
+// sendQuorum asks every replica that has not answered yet, until the operation finishes.
+func (n *Node) sendQuorum(op *quorumOp) {
+	if op.finished {
+		return
+	}
+	put, isPut := op.payload.(quorumPut)
+	var fallbacks []string
+	if isPut && n.cluster.HintedHandoff {
+		fallbacks = n.fallbacks(put.Key)
+	}
+	for _, id := range op.replicas {
+		if op.replies[id] {
+			continue
+		}
+		if isPut && n.cluster.HintedHandoff && n.suspects(id) {
+			fallbacks = n.handOff(put, id, fallbacks)
+			continue
+		}
+		n.send(id, op.payload)
+	}
+	n.cluster.net.After(n.cluster.Retry, func() { n.sendQuorum(op) })
+}
+
+func (n *Node) onQuorumReply(from string, r quorumReply) {
+	replica := from
+	if r.For != "" {
+		replica = r.For
+	}
+	op, ok := n.quorums[r.Req]
+	if !ok || op.replies[replica] {
+		return
+	}
+	op.replies[replica] = true
+	if _, isGet := op.payload.(quorumGet); isGet {
+		op.seen[replica] = r.Versions
+	}
+	for _, v := range r.Versions {
+		op.versions = addVersion(op.versions, v)
+	}
+	if len(op.replies) >= op.need {
+		n.finishQuorum(r.Req, nil)
+	}
+}
+
+func (n *Node) finishQuorum(req uint64, err error) {
+	op, ok := n.quorums[req]
+	if !ok {
+		return
+	}
+	delete(n.quorums, req)
+	op.finished = true
+	if get, isGet := op.payload.(quorumGet); isGet && err == nil && n.cluster.ReadRepair {
+		n.readRepair(get.Key, op)
+	}
+	op.done(op, err)
+}
+
+// SimulateQuorumReplication stores a key on N of five nodes and shows how R and W trade consistency for availability.
+func SimulateQuorumReplication(ctx context.Context, cfg config.Network, replicas int) error {
+	c := newCluster(cfg, "A", "B", "C", "D", "E")
+	if replicas < 2 || replicas > len(c.Nodes()) {
+		return fmt.Errorf("replicas must be between 2 and %d, got %d", len(c.Nodes()), replicas)
+	}
+	c.ReplicationFactor = replicas
+	prefs := c.PreferenceList(dataKey, replicas)
+	majority, lone := prefs[0], prefs[len(prefs)-1]
+	strong := replicas/2 + 1
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: %s is stored on %v (N=%d)", systemQuorum, dataKey, prefs, replicas)})
+
+	writeQuorum(ctx, systemQuorum, c, majority, "v1", strong)
+	readQuorum(ctx, systemQuorum, c, lone, strong)
+
+	// Isolate one replica from every other node
+	var rest []string
+	for _, n := range c.Nodes() {
+		if n.ID != lone {
+			rest = append(rest, n.ID)
+		}
+	}
+	partition(ctx, systemQuorum, c, rest, []string{lone})
+
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: R=%d W=%d, R+W>N: reads see completed writes, only where a quorum is reachable", systemQuorum, strong, strong)})
+	writeQuorum(ctx, systemQuorum, c, majority, "v2", strong)
+	writeQuorum(ctx, systemQuorum, c, lone, "v2 from the minority", strong)
+	readQuorum(ctx, systemQuorum, c, majority, strong)
+	readQuorum(ctx, systemQuorum, c, lone, strong)
+
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: R=1 W=1, R+W<=N: always available, reads may be stale", systemQuorum)})
+	writeQuorum(ctx, systemQuorum, c, lone, "v3 from the minority", 1)
+	readQuorum(ctx, systemQuorum, c, majority, 1)
+	readQuorum(ctx, systemQuorum, c, lone, 1)
+
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: W=%d: every replica must acknowledge, like the CP system", systemQuorum, replicas)})
+	writeQuorum(ctx, systemQuorum, c, majority, "v4", replicas)
+	showReplicas(ctx, systemQuorum, c, dataKey)
+
+	if err := c.Run(ctx, 2*time.Second); err != nil {
+		return err
+	}
+	heal(ctx, systemQuorum, c)
+
+	// A read quorum spanning both sides sees the writes of the minority as siblings
+	readQuorum(ctx, systemQuorum, c, majority, replicas)
+	showNetwork(ctx, systemQuorum, c)
+	_, err := checkHistory(ctx, systemQuorum, c)
+	return err
+}
+
+func writeQuorum(ctx context.Context, system string, c *Cluster, nodeID, newData string, w int) error {
+	err := c.await(func(done func(error)) { c.Node(nodeID).putQuorum(dataKey, newData, nil, w, done) })
+	if err != nil {
+		events.Emit(ctx, events.WriteRejected{System: system, Node: nodeID, Value: newData, Reason: fmt.Sprintf("W=%d: %v", w, err)})
+		return err
+	}
+	events.Emit(ctx, events.WriteAccepted{System: system, Node: nodeID, Value: newData})
+	return nil
+}
+
+func readQuorum(ctx context.Context, system string, c *Cluster, nodeID string, r int) ([]string, error) {
+	var values []string
+	err := c.await(func(done func(error)) {
+		c.Node(nodeID).getQuorum(dataKey, r, func(versions []Version, err error) {
+			values = siblingValues(versions)
+			done(err)
+		})
+	})
+	if err != nil {
+		events.Emit(ctx, events.ReadRejected{System: system, Node: nodeID, Reason: fmt.Sprintf("R=%d: %v", r, err)})
+		return nil, err
+	}
+	if len(values) > 1 {
+		// Blind writes on both sides of the partition were concurrent
+		events.Emit(ctx, events.SiblingsFound{System: system, Node: nodeID, Key: dataKey, Siblings: values})
+		return values, nil
+	}
+	events.Emit(ctx, events.ReadServed{System: system, Node: nodeID, Value: strings.Join(values, "")})
+	return values, nil
+}
@@ -0,0 +1,65 @@
+package capTheorem
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"GoBestPratices/config"
+)
+
+func TestQuorum(t *testing.T) {
+	c := newCluster(config.Network{Seed: 1}, "A", "B", "C", "D", "E")
+	c.ReplicationFactor = 3
+	prefs := c.PreferenceList(dataKey, 3)
+	if len(prefs) != 3 {
+		t.Fatalf("PreferenceList = %v; want 3 nodes", prefs)
+	}
+	first, lone := prefs[0], prefs[2]
+	var rest []string
+	for _, n := range c.Nodes() {
+		if n.ID != lone {
+			rest = append(rest, n.ID)
+		}
+	}
+
+	put := func(node, value string, w int) error {
+		return c.await(func(done func(error)) { c.Node(node).putQuorum(dataKey, value, nil, w, done) })
+	}
+	get := func(node string, r int) (string, error) {
+		var v string
+		err := c.await(func(done func(error)) {
+			c.Node(node).getQuorum(dataKey, r, func(versions []Version, err error) {
+				v = strings.Join(siblingValues(versions), " | ")
+				done(err)
+			})
+		})
+		return v, err
+	}
+
+	if err := put(first, "v1", 2); err != nil {
+		t.Fatal(err)
+	}
+	c.Network().Partition(rest, []string{lone})
+
+	steps := []struct {
+		name    string
+		op      func() (string, error)
+		want    string
+		wantErr error
+	}{
+		{"W=2 on the majority", func() (string, error) { return "", put(first, "v2", 2) }, "", nil},
+		{"R=2 on the majority sees it", func() (string, error) { return get(first, 2) }, "v2", nil},
+		{"R=2 on the minority", func() (string, error) { return get(lone, 2) }, "", ErrUnavailable},
+		{"W=1 on the minority", func() (string, error) { return "", put(lone, "v3", 1) }, "", nil},
+		{"R=1 on the majority is stale", func() (string, error) { return get(first, 1) }, "v2", nil},
+		{"W=3 on the majority", func() (string, error) { return "", put(first, "v4", 3) }, "", ErrUnavailable},
+		{"R=4 is not a quorum of N=3", func() (string, error) { return get(first, 4) }, "", ErrInvalidQuorum},
+	}
+	for _, s := range steps {
+		got, err := s.op()
+		if !errors.Is(err, s.wantErr) || got != s.want {
+			t.Errorf("%s = %q, %v; want %q, %v", s.name, got, err, s.want, s.wantErr)
+		}
+	}
+}
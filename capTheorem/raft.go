@@ -0,0 +1,505 @@
+package capTheorem
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+//Raft
+//Raft keeps the replicated log consistent with a single leader per term.
+//Leader election: a follower that hears nothing from a leader for a random election timeout asks the others for votes;
+//it needs a majority, and nodes only vote for a candidate whose log is at least as up to date as theirs.
+//A pre-vote round first checks that a majority would vote, so a node returning from a partition cannot disrupt a healthy leader.
+//Log replication: the leader appends commands to its log and sends them to the followers, who only accept entries that extend
+//a log matching the leader's; once a majority stored an entry of its term, the leader advances the commit index and everyone applies it.
+//Terms: every message carries the sender's term; a node that sees a newer term steps down to follower, and messages of older terms are ignored.
+//A leader cut off from the majority cannot commit anything, so the minority side refuses writes and reads while the majority elects a new leader.
+//Log compaction: once enough entries are applied, a node replaces them with a snapshot of its state machine.
+//A follower that lags behind the start of the leader's log receives the snapshot instead (InstallSnapshot).
+
+// maxAppend bounds the entries sent in one AppendEntries message.
+const maxAppend = 64
+
+type raftRole int
+
+const (
+	follower raftRole = iota
+	candidate
+	leader
+)
+
+type raftEntry struct {
+	Term uint64
+	Cmd  Command
+}
+
+// requestVote asks for a vote in Term; with PreVote it only asks whether the vote would be granted.
+type requestVote struct {
+	Term      uint64
+	PreVote   bool
+	LastIndex uint64
+	LastTerm  uint64
+}
+
+type voteReply struct {
+	Term    uint64
+	PreVote bool
+	Granted bool
+}
+
+// appendEntries replicates the entries following PrevIndex and doubles as the leader's heartbeat.
+type appendEntries struct {
+	Term      uint64
+	PrevIndex uint64
+	PrevTerm  uint64
+	Entries   []raftEntry
+	Commit    uint64
+}
+
+// appendReply returns the index of the follower's log known to match the leader's on success,
+// and the last index worth trying otherwise.
+type appendReply struct {
+	Term    uint64
+	Success bool
+	Match   uint64
+}
+
+// installSnapshot replaces the log of a follower that lags behind the leader's snapshot.
+type installSnapshot struct {
+	Term     uint64
+	Snapshot Snapshot
+}
+
+// forwardCommand passes a command submitted on a follower to the leader.
+type forwardCommand struct {
+	Cmd Command
+}
+
+// raft is the Raft state of one node.
+type raft struct {
+	node     *Node
+	term     uint64
+	votedFor string
+	// log[0] stands for the last entry covered by the snapshot, at snapIndex (0 before any snapshot)
+	log       []raftEntry
+	snapIndex uint64
+	snapshot  Snapshot
+	commit    uint64
+	applied   uint64
+	installed int
+
+	role        raftRole
+	leader      string
+	lastContact time.Time
+	votes       map[string]bool
+	preVotes    map[string]bool
+	election    *Timer
+
+	// Leader state: the next entry to send each follower and the last one known to be stored
+	next     map[string]uint64
+	match    map[string]uint64
+	appended map[CommandID]bool
+
+	pending pendingCommands
+}
+
+// StartRaft runs Raft on every node of the cluster.
+func (c *Cluster) StartRaft() {
+	for _, n := range c.nodes {
+		r := &raft{
+			node:    n,
+			log:     []raftEntry{{}},
+			pending: make(pendingCommands),
+		}
+		n.consensus = r
+		r.resetElectionTimer()
+	}
+}
+
+// Leader returns the node with the highest term among those that believe they lead, and that term.
+func (c *Cluster) Leader() (string, uint64) {
+	var id string
+	var term uint64
+	for _, n := range c.nodes {
+		if n.consensus == nil {
+			continue
+		}
+		if l, t := n.consensus.Leader(); l == n.ID && t >= term {
+			id, term = l, t
+		}
+	}
+	return id, term
+}
+
+func (r *raft) Leader() (string, uint64) {
+	return r.leader, r.term
+}
+
+func (r *raft) lastIndex() uint64 {
+	return r.snapIndex + uint64(len(r.log)-1)
+}
+
+// entry returns the entry at index i, which must not be older than the snapshot.
+func (r *raft) entry(i uint64) raftEntry {
+	return r.log[i-r.snapIndex]
+}
+
+func (r *raft) termAt(i uint64) uint64 {
+	return r.entry(i).Term
+}
+
+func (r *raft) majority() int {
+	return len(r.node.cluster.nodes)/2 + 1
+}
+
+func (r *raft) Propose(cmd Command, done func(string, error)) {
+	r.submit(r.pending.add(r.node, cmd, done, func() string { return r.leader }))
+}
+
+// submit appends cmd if the node leads, or forwards it to the leader, until the command completes.
+func (r *raft) submit(cmd Command) {
+	if !r.pending.waiting(cmd.ID) {
+		return
+	}
+	switch {
+	case r.role == leader:
+		r.appendCommand(cmd)
+	case r.leader != "":
+		r.node.send(r.leader, forwardCommand{Cmd: cmd})
+	}
+	r.node.cluster.net.After(r.node.cluster.Retry, func() { r.submit(cmd) })
+}
+
+func (r *raft) appendCommand(cmd Command) {
+	if r.node.applied(cmd.ID) || r.appended[cmd.ID] {
+		return
+	}
+	r.appended[cmd.ID] = true
+	r.log = append(r.log, raftEntry{Term: r.term, Cmd: cmd})
+	r.advanceCommit()
+	r.replicate()
+}
+
+func (r *raft) receive(from string, payload any) {
+	switch m := payload.(type) {
+	case requestVote:
+		r.onRequestVote(from, m)
+	case voteReply:
+		r.onVoteReply(from, m)
+	case appendEntries:
+		r.onAppendEntries(from, m)
+	case appendReply:
+		r.onAppendReply(from, m)
+	case installSnapshot:
+		r.onInstallSnapshot(from, m)
+	case forwardCommand:
+		if r.role == leader {
+			r.appendCommand(m.Cmd)
+		}
+	}
+}
+
+// restart makes the node a follower that knows no leader, fails the commands submitted on it, and forgets the commit index.
+// The term, the vote, the log and the snapshot are durable: the state machine is rebuilt from the snapshot,
+// and the entries after it are applied again once the leader tells what is committed.
+func (r *raft) restart() {
+	r.pending.fail(ErrCrashed)
+	r.role = follower
+	r.leader = ""
+	r.votes, r.preVotes = nil, nil
+	r.next, r.match, r.appended = nil, nil, nil
+	r.commit, r.applied = r.snapIndex, r.snapIndex
+	r.node.restore(r.snapshot)
+	r.resetElectionTimer()
+}
+
+// Elections
+
+func (r *raft) resetElectionTimer() {
+	if r.election != nil {
+		r.election.Stop()
+	}
+	c := r.node.cluster
+	d := c.ElectionTimeout + time.Duration(c.net.Rand().Int63n(int64(c.ElectionTimeout)))
+	r.election = c.net.After(d, r.preVote)
+}
+
+// hasLeader reports whether the node leads or recently heard from a leader.
+func (r *raft) hasLeader() bool {
+	if r.role == leader {
+		return true
+	}
+	return r.leader != "" && r.node.now().Sub(r.lastContact) < r.node.cluster.ElectionTimeout
+}
+
+func (r *raft) preVote() {
+	r.leader = ""
+	r.preVotes = map[string]bool{r.node.ID: true}
+	r.resetElectionTimer()
+	if len(r.preVotes) >= r.majority() {
+		r.campaign()
+		return
+	}
+	r.node.broadcast(requestVote{Term: r.term + 1, PreVote: true, LastIndex: r.lastIndex(), LastTerm: r.termAt(r.lastIndex())})
+}
+
+func (r *raft) campaign() {
+	r.preVotes = nil
+	r.term++
+	r.role = candidate
+	r.votedFor = r.node.ID
+	r.votes = map[string]bool{r.node.ID: true}
+	r.resetElectionTimer()
+	if len(r.votes) >= r.majority() {
+		r.becomeLeader()
+		return
+	}
+	r.node.broadcast(requestVote{Term: r.term, LastIndex: r.lastIndex(), LastTerm: r.termAt(r.lastIndex())})
+}
+
+func (r *raft) onRequestVote(from string, m requestVote) {
+	upToDate := m.LastTerm > r.termAt(r.lastIndex()) ||
+		m.LastTerm == r.termAt(r.lastIndex()) && m.LastIndex >= r.lastIndex()
+	if m.PreVote {
+		// A pre-vote changes nothing: it only tells whether a real vote would be granted
+		granted := m.Term > r.term && upToDate && !r.hasLeader()
+		r.node.send(from, voteReply{Term: m.Term, PreVote: true, Granted: granted})
+		return
+	}
+	if m.Term > r.term {
+		r.stepDown(m.Term)
+	}
+	granted := m.Term == r.term && (r.votedFor == "" || r.votedFor == from) && upToDate
+	if granted {
+		r.votedFor = from
+		r.resetElectionTimer()
+	}
+	r.node.send(from, voteReply{Term: r.term, Granted: granted})
+}
+
+func (r *raft) onVoteReply(from string, m voteReply) {
+	if m.PreVote {
+		if r.preVotes != nil && m.Term == r.term+1 && m.Granted {
+			r.preVotes[from] = true
+			if len(r.preVotes) >= r.majority() {
+				r.campaign()
+			}
+		}
+		return
+	}
+	if m.Term > r.term {
+		r.stepDown(m.Term)
+		return
+	}
+	if r.role != candidate || m.Term != r.term || !m.Granted {
+		return
+	}
+	r.votes[from] = true
+	if len(r.votes) >= r.majority() {
+		r.becomeLeader()
+	}
+}
+
+// stepDown makes the node a follower, in term if it is newer.
+func (r *raft) stepDown(term uint64) {
+	if term > r.term {
+		r.term = term
+		r.votedFor = ""
+		r.leader = ""
+	}
+	r.role = follower
+	r.preVotes = nil
+	r.resetElectionTimer()
+}
+
+func (r *raft) becomeLeader() {
+	r.role = leader
+	r.leader = r.node.ID
+	r.election.Stop()
+	r.next = make(map[string]uint64)
+	r.match = make(map[string]uint64)
+	for _, peer := range r.node.cluster.peers(r.node.ID) {
+		r.next[peer.ID] = r.lastIndex() + 1
+	}
+	r.appended = make(map[CommandID]bool)
+	// A no-op of the new term commits the entries left by previous leaders
+	r.log = append(r.log, raftEntry{Term: r.term})
+	r.advanceCommit()
+
+	term := r.term
+	var beat func()
+	beat = func() {
+		if r.role != leader || r.term != term {
+			return
+		}
+		r.replicate()
+		r.node.cluster.net.After(r.node.cluster.Heartbeat, beat)
+	}
+	beat()
+}
+
+// Log replication
+
+func (r *raft) replicate() {
+	for _, peer := range r.node.cluster.peers(r.node.ID) {
+		r.sendAppend(peer.ID)
+	}
+}
+
+// sendAppend sends peer the entries from its next index on, or the snapshot if they were compacted,
+// and assumes they will arrive.
+func (r *raft) sendAppend(peer string) {
+	next := r.next[peer]
+	if next <= r.snapIndex {
+		r.node.send(peer, installSnapshot{Term: r.term, Snapshot: r.snapshot})
+		r.next[peer] = r.snapIndex + 1
+		return
+	}
+	end := min(next+maxAppend, r.lastIndex()+1)
+	r.node.send(peer, appendEntries{
+		Term:      r.term,
+		PrevIndex: next - 1,
+		PrevTerm:  r.termAt(next - 1),
+		Entries:   slices.Clone(r.log[next-r.snapIndex : end-r.snapIndex]),
+		Commit:    r.commit,
+	})
+	r.next[peer] = end
+}
+
+// follow handles a message from the leader of term and reports whether it is current.
+func (r *raft) follow(from string, term uint64) bool {
+	if term < r.term {
+		r.node.send(from, appendReply{Term: r.term})
+		return false
+	}
+	if term > r.term || r.role != follower {
+		r.stepDown(term)
+	}
+	r.leader = from
+	r.lastContact = r.node.now()
+	r.resetElectionTimer()
+	return true
+}
+
+func (r *raft) onAppendEntries(from string, m appendEntries) {
+	if !r.follow(from, m.Term) {
+		return
+	}
+	if m.PrevIndex < r.snapIndex {
+		// Entries up to the snapshot are committed, so they match the leader's
+		skip := r.snapIndex - m.PrevIndex
+		if skip >= uint64(len(m.Entries)) {
+			r.node.send(from, appendReply{Term: r.term, Success: true, Match: m.PrevIndex + uint64(len(m.Entries))})
+			return
+		}
+		m.Entries = m.Entries[skip:]
+		m.PrevIndex, m.PrevTerm = r.snapIndex, r.termAt(r.snapIndex)
+	}
+	if m.PrevIndex > r.lastIndex() || r.termAt(m.PrevIndex) != m.PrevTerm {
+		r.node.send(from, appendReply{Term: r.term, Match: min(r.lastIndex(), m.PrevIndex-1)})
+		return
+	}
+	for i, e := range m.Entries {
+		index := m.PrevIndex + 1 + uint64(i)
+		if index <= r.lastIndex() {
+			if r.termAt(index) == e.Term {
+				continue
+			}
+			// A conflicting entry and everything after it were never committed
+			r.log = r.log[:index-r.snapIndex]
+		}
+		r.log = append(r.log, e)
+	}
+	last := m.PrevIndex + uint64(len(m.Entries))
+	if m.Commit > r.commit {
+		r.commit = max(r.commit, min(m.Commit, last))
+		r.applyCommitted()
+	}
+	r.node.send(from, appendReply{Term: r.term, Success: true, Match: last})
+}
+
+func (r *raft) onInstallSnapshot(from string, m installSnapshot) {
+	if !r.follow(from, m.Term) {
+		return
+	}
+	snap := m.Snapshot
+	if snap.Index > r.commit {
+		if snap.Index < r.lastIndex() && r.termAt(snap.Index) == snap.Term {
+			// Entries after the snapshot may still be useful
+			r.log = append([]raftEntry{{Term: snap.Term}}, r.log[snap.Index-r.snapIndex+1:]...)
+		} else {
+			r.log = []raftEntry{{Term: snap.Term}}
+		}
+		r.snapIndex, r.snapshot = snap.Index, snap
+		r.commit, r.applied = snap.Index, snap.Index
+		r.installed++
+		r.node.restore(snap)
+		r.pending.completeRestored(r.node)
+	}
+	r.node.send(from, appendReply{Term: r.term, Success: true, Match: snap.Index})
+}
+
+func (r *raft) onAppendReply(from string, m appendReply) {
+	if m.Term > r.term {
+		r.stepDown(m.Term)
+		return
+	}
+	if r.role != leader || m.Term != r.term {
+		return
+	}
+	if !m.Success {
+		// Back up to the follower's hint and try again
+		r.next[from] = max(m.Match, r.match[from]) + 1
+		r.sendAppend(from)
+		return
+	}
+	if m.Match > r.match[from] {
+		r.match[from] = m.Match
+		r.advanceCommit()
+	}
+	if r.next[from] <= r.lastIndex() {
+		r.sendAppend(from)
+	}
+}
+
+// advanceCommit commits the last entry of the current term stored by a majority, and everything before it.
+func (r *raft) advanceCommit() {
+	for i := r.lastIndex(); i > r.commit && r.termAt(i) == r.term; i-- {
+		stored := 1
+		for _, match := range r.match {
+			if match >= i {
+				stored++
+			}
+		}
+		if stored >= r.majority() {
+			r.commit = i
+			r.applyCommitted()
+			// Let the followers apply it too without waiting for the next heartbeat
+			r.replicate()
+			return
+		}
+	}
+}
+
+func (r *raft) applyCommitted() {
+	for r.applied < r.commit {
+		r.applied++
+		cmd := r.entry(r.applied).Cmd
+		result, err := r.node.applyCommand(cmd)
+		r.pending.complete(cmd.ID, result, err)
+	}
+	if r.applied-r.snapIndex >= uint64(r.node.cluster.SnapshotThreshold) {
+		r.compact()
+	}
+}
+
+// compact replaces the applied entries with a snapshot of the state machine.
+func (r *raft) compact() {
+	term := r.termAt(r.applied)
+	r.snapshot = r.node.snapshot(r.applied, term)
+	r.log = append([]raftEntry{{Term: term}}, r.log[r.applied-r.snapIndex+1:]...)
+	r.snapIndex = r.applied
+	// The sessions now tell which of the compacted commands were applied
+	maps.DeleteFunc(r.appended, func(id CommandID, _ bool) bool { return r.node.applied(id) })
+}
@@ -0,0 +1,63 @@
+package capTheorem
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+)
+
+func TestRaftSnapshotCatchUp(t *testing.T) {
+	c := newCluster(config.Network{Seed: 5, Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond}, "A", "B", "C")
+	c.SnapshotThreshold = 200
+	c.StartRaft()
+	if !c.Network().RunUntil(func() bool { id, _ := c.Leader(); return id != "" }, time.Second) {
+		t.Fatal("no leader elected")
+	}
+	leader, _ := c.Leader()
+	var lagging string
+	for _, n := range c.Nodes() {
+		if n.ID != leader {
+			lagging = n.ID
+		}
+	}
+	var rest []string
+	for _, n := range c.Nodes() {
+		if n.ID != lagging {
+			rest = append(rest, n.ID)
+		}
+	}
+	c.Network().Partition(rest, []string{lagging})
+
+	const writes = 3000
+	for i := 0; i < writes; i++ {
+		cmd := Command{Op: "put", Key: catalogKey(i % 50), Value: fmt.Sprint(i)}
+		if _, err := c.propose(leader, cmd); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	lead := c.Node(leader).consensus.(*raft)
+	if n := len(lead.log); n > c.SnapshotThreshold+1 {
+		t.Errorf("leader keeps %d log entries; want at most %d after compaction", n, c.SnapshotThreshold+1)
+	}
+	if n := len(lead.snapshot.Sessions); n != 1 {
+		t.Errorf("snapshot holds %d sessions after %d writes from one node; want 1", n, writes)
+	}
+
+	c.Network().Heal()
+	follower := c.Node(lagging).consensus.(*raft)
+	if !c.Network().RunUntil(func() bool { return follower.applied >= lead.commit }, 5*time.Second) {
+		t.Fatalf("follower applied %d of %d entries", follower.applied, lead.commit)
+	}
+	if follower.installed == 0 {
+		t.Error("follower caught up without a snapshot")
+	}
+	for i := 0; i < 50; i++ {
+		want, _ := c.Node(leader).Get(catalogKey(i))
+		if got, _ := c.Node(lagging).Get(catalogKey(i)); got != want {
+			t.Errorf("%s on the follower = %q; want %q", catalogKey(i), got, want)
+		}
+	}
+	checkLogs(t, c)
+}
@@ -0,0 +1,153 @@
+package capTheorem
+
+import (
+	"context"
+	"strings"
+
+	"GoBestPratices/config"
+	"GoBestPratices/registry"
+)
+
+func init() {
+	registry.Register(registry.Example{
+		Name:        "cap-cp",
+		Category:    "cap-theorem",
+		Description: "CP system during a network partition",
+		Params: append(networkParams(),
+			registry.Param{Name: "engine", Type: registry.String, Default: "raft", Usage: "consensus engine", Choices: []string{"raft", "paxos"}}),
+		Run: func(ctx context.Context, args registry.Args) error {
+			return SimulateNetworkPartitionCP(ctx, networkConfig(args), args.String("engine"))
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "cap-ap",
+		Category:    "cap-theorem",
+		Description: "AP system during a network partition",
+		Params:      networkParams(),
+		Run: func(ctx context.Context, args registry.Args) error {
+			return SimulateNetworkPartitionAP(ctx, networkConfig(args))
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "cap-ca",
+		Category:    "cap-theorem",
+		Description: "CA system during a network partition",
+		Params:      append(networkParams(), detectorParams()...),
+		Run: func(ctx context.Context, args registry.Args) error {
+			return SimulateNetworkPartitionCA(ctx, networkConfig(args), detectorConfig(args))
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "cap-quorum",
+		Category:    "cap-theorem",
+		Description: "Dynamo-style N/R/W quorums during a network partition",
+		Params: append(networkParams(),
+			registry.Param{Name: "replicas", Type: registry.Int, Default: "3", Usage: "replicas per key (N), out of 5 nodes"}),
+		Run: func(ctx context.Context, args registry.Args) error {
+			return SimulateQuorumReplication(ctx, networkConfig(args), args.Int("replicas"))
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "cap-handoff",
+		Category:    "cap-theorem",
+		Description: "Hinted handoff and read repair bringing a replica up to date",
+		Params:      append(networkParams(), detectorParams()...),
+		Run: func(ctx context.Context, args registry.Args) error {
+			return SimulateHintedHandoff(ctx, networkConfig(args), detectorConfig(args))
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "cap-session",
+		Category:    "cap-theorem",
+		Description: "Session guarantees on the AP replicas during a network partition",
+		Params:      networkParams(),
+		Run: func(ctx context.Context, args registry.Args) error {
+			return SimulateSessionGuarantees(ctx, networkConfig(args))
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "cap-causal",
+		Category:    "cap-theorem",
+		Description: "A reply visible before its post under AP, and not in causal mode",
+		Params:      networkParams(),
+		Run: func(ctx context.Context, args registry.Args) error {
+			return SimulateCausalConsistency(ctx, networkConfig(args))
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "cap-detector",
+		Category:    "cap-theorem",
+		Description: "Phi-accrual failure detection of a slow node and of a crashed one",
+		Params:      append(networkParams(), detectorParams()...),
+		Run: func(ctx context.Context, args registry.Args) error {
+			return SimulateFailureDetector(ctx, networkConfig(args), detectorConfig(args))
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "cap-jepsen",
+		Category:    "cap-theorem",
+		Description: "Random clients and a nemesis injecting faults, checked for linearizability",
+		Params: append(append(networkParams(), detectorParams()...),
+			registry.Param{Name: "system", Type: registry.String, Default: "raft", Usage: "system under test", Choices: []string{"raft", "paxos", "quorum", "ca"}},
+			registry.Param{Name: "clients", Type: registry.Int, Default: "5", Usage: "concurrent clients"},
+			registry.Param{Name: "duration", Type: registry.Duration, Default: "10s", Usage: "virtual time the clients run"},
+			registry.Param{Name: "faults", Type: registry.String, Default: strings.Join(Faults, ","), Usage: "faults the nemesis injects, comma separated"}),
+		Run: func(ctx context.Context, args registry.Args) error {
+			w := DefaultWorkload(args.String("system"))
+			w.Clients = args.Int("clients")
+			w.Duration = args.Duration("duration")
+			w.Detector = detectorConfig(args)
+			w.Faults = nil
+			for _, f := range strings.Split(args.String("faults"), ",") {
+				if f = strings.TrimSpace(f); f != "" {
+					w.Faults = append(w.Faults, f)
+				}
+			}
+			return SimulateJepsen(ctx, networkConfig(args), w)
+		},
+	})
+}
+
+// networkParams are the flags shared by every simulation on the simulated network.
+func networkParams() []registry.Param {
+	return []registry.Param{
+		{Name: "seed", Type: registry.Int, Default: "1", Usage: "seed of the simulated network", Key: "network.seed"},
+		{Name: "latency", Type: registry.Duration, Default: "10ms", Usage: "mean one-way message latency", Key: "network.latency"},
+		{Name: "jitter", Type: registry.Duration, Default: "5ms", Usage: "standard deviation of the latency", Key: "network.jitter"},
+		{Name: "drop", Type: registry.Float, Default: "0", Usage: "probability a message is lost", Key: "network.drop"},
+		{Name: "duplicate", Type: registry.Float, Default: "0", Usage: "probability a message is delivered twice", Key: "network.duplicate"},
+		{Name: "reorder", Type: registry.Float, Default: "0", Usage: "probability a message is overtaken by later ones", Key: "network.reorder"},
+	}
+}
+
+func networkConfig(args registry.Args) config.Network {
+	return config.Network{
+		Seed:      args.Int("seed"),
+		Latency:   args.Duration("latency"),
+		Jitter:    args.Duration("jitter"),
+		Drop:      args.Float("drop"),
+		Duplicate: args.Float("duplicate"),
+		Reorder:   args.Float("reorder"),
+	}
+}
+
+// detectorParams are the flags of the simulations whose nodes run a failure detector.
+func detectorParams() []registry.Param {
+	return []registry.Param{
+		{Name: "threshold", Type: registry.Float, Default: "8", Usage: "phi at which a node suspects a peer", Key: "detector.threshold"},
+		{Name: "heartbeat", Type: registry.Duration, Default: "50ms", Usage: "interval between heartbeats", Key: "detector.heartbeat"},
+		{Name: "min-stddev", Type: registry.Duration, Default: "10ms", Usage: "lower bound of the heartbeat gaps' standard deviation", Key: "detector.min_stddev"},
+		{Name: "acceptable-pause", Type: registry.Duration, Default: "0s", Usage: "extra gap tolerated before phi rises", Key: "detector.acceptable_pause"},
+		{Name: "window", Type: registry.Int, Default: "50", Usage: "heartbeat gaps the detector remembers", Key: "detector.window"},
+	}
+}
+
+func detectorConfig(args registry.Args) config.Detector {
+	return config.Detector{
+		Threshold:       args.Float("threshold"),
+		Heartbeat:       args.Duration("heartbeat"),
+		MinStdDev:       args.Duration("min-stddev"),
+		AcceptablePause: args.Duration("acceptable-pause"),
+		Window:          args.Int("window"),
+	}
+}
@@ -0,0 +1,242 @@
+package capTheorem
+
+import (
+	"errors"
+	"fmt"
+
+	"GoBestPratices/capTheorem/linearizability"
+)
+
+//Synchronous replication
+//A write is applied everywhere or nowhere, using the same two phases as consistency.TwoPhaseCommit.
+//The coordinator stages the write on every replica (prepare), then tells them to apply or drop it (decision).
+//A replica that voted yes cannot know the outcome until the decision arrives, so reads of that key wait for it.
+//The client is told about a commit once every replica acknowledged it; if that takes too long it only knows the write may have happened.
+
+var (
+	// ErrConflict is returned when another write to the same key is still being decided.
+	ErrConflict = errors.New("conflicting write in progress")
+	// ErrInDoubt is returned when a read waited too long for a pending write to be decided.
+	ErrInDoubt = errors.New("pending write still in doubt")
+)
+
+// opID identifies a synchronous write across the cluster.
+type opID struct {
+	Node string
+	Seq  uint64
+}
+
+type prepare struct {
+	Op      opID
+	Key     string
+	Version Version
+}
+
+type vote struct {
+	Op  opID
+	Yes bool
+}
+
+type decision struct {
+	Op     opID
+	Key    string
+	Commit bool
+}
+
+type decisionAck struct {
+	Op opID
+}
+
+// stagedWrite is a write a replica voted for but does not know the outcome of yet.
+type stagedWrite struct {
+	Op      opID
+	Version Version
+}
+
+// writeOp is the coordinator's view of a synchronous write.
+type writeOp struct {
+	id      opID
+	key     string
+	version Version
+	votes   map[string]bool
+	pending map[string]bool // peers that have not acknowledged the decision
+	decided bool
+	commit  bool
+	done    func(error)
+	timer   *Timer
+}
+
+// writeSync replicates a write to every node or to none of them, then calls done.
+func (n *Node) writeSync(key, value string, done func(error)) {
+	record := n.cluster.invoke(n.ID, linearizability.KVInput{Op: "put", Key: key, Value: value})
+	reply := done
+	done = func(err error) {
+		if err != nil {
+			// The write was refused or aborted: no replica applies it
+			record(value, notApplied{err})
+		} else {
+			record(value, nil)
+		}
+		reply(err)
+	}
+	if _, busy := n.staged[key]; busy {
+		done(ErrConflict)
+		return
+	}
+	n.seq++
+	op := &writeOp{
+		id:      opID{Node: n.ID, Seq: n.seq},
+		key:     key,
+		version: n.newVersion(key, value, mergeContext(n.store[key])),
+		votes:   make(map[string]bool),
+		done:    done,
+	}
+	n.ops[op.id] = op
+	n.staged[key] = stagedWrite{Op: op.id, Version: op.version}
+	if len(n.cluster.peers(n.ID)) == 0 {
+		n.decide(op, nil)
+		return
+	}
+	op.timer = n.cluster.net.After(n.cluster.Timeout, func() {
+		n.decide(op, fmt.Errorf("%w: %w, a replica did not vote", ErrUnavailable, ErrTimeout))
+	})
+	n.sendPrepares(op)
+}
+
+// sendPrepares asks every replica that has not voted yet, until the write is decided.
+func (n *Node) sendPrepares(op *writeOp) {
+	if op.decided {
+		return
+	}
+	for _, peer := range n.cluster.peers(n.ID) {
+		if !op.votes[peer.ID] {
+			n.send(peer.ID, prepare{Op: op.id, Key: op.key, Version: op.version})
+		}
+	}
+	n.cluster.net.After(n.cluster.Retry, func() { n.sendPrepares(op) })
+}
+
+func (n *Node) onPrepare(from string, p prepare) {
+	if n.finished[p.Op] {
+		// A retransmission that arrived after the decision
+		return
+	}
+	if s, busy := n.staged[p.Key]; busy && s.Op != p.Op {
+		n.send(from, vote{Op: p.Op, Yes: false})
+		return
+	}
+	n.staged[p.Key] = stagedWrite{Op: p.Op, Version: p.Version}
+	n.send(from, vote{Op: p.Op, Yes: true})
+}
+
+func (n *Node) onVote(from string, v vote) {
+	op, ok := n.ops[v.Op]
+	if !ok || op.decided {
+		return
+	}
+	if !v.Yes {
+		n.decide(op, ErrConflict)
+		return
+	}
+	op.votes[from] = true
+	if len(op.votes) == len(n.cluster.peers(n.ID)) {
+		n.decide(op, nil)
+	}
+}
+
+// decide commits the write when err is nil and aborts it otherwise.
+func (n *Node) decide(op *writeOp, err error) {
+	if op.decided {
+		return
+	}
+	op.decided, op.commit = true, err == nil
+	if op.timer != nil {
+		op.timer.Stop()
+	}
+	n.resolve(op.key, op.id, op.commit)
+	op.pending = make(map[string]bool)
+	for _, peer := range n.cluster.peers(n.ID) {
+		op.pending[peer.ID] = true
+	}
+	if !op.commit {
+		op.done(err)
+	}
+	n.sendDecisions(op)
+}
+
+// sendDecisions repeats the outcome to every replica until each one acknowledged it.
+func (n *Node) sendDecisions(op *writeOp) {
+	if len(op.pending) == 0 {
+		delete(n.ops, op.id)
+		if op.commit {
+			// The client hears about a commit once every replica applied it
+			op.done(nil)
+		}
+		return
+	}
+	// Peers in cluster order, so the run stays reproducible from its seed
+	for _, peer := range n.cluster.peers(n.ID) {
+		if op.pending[peer.ID] {
+			n.send(peer.ID, decision{Op: op.id, Key: op.key, Commit: op.commit})
+		}
+	}
+	n.cluster.net.After(n.cluster.Retry, func() { n.sendDecisions(op) })
+}
+
+func (n *Node) onDecision(from string, d decision) {
+	n.resolve(d.Key, d.Op, d.Commit)
+	n.send(from, decisionAck{Op: d.Op})
+}
+
+func (n *Node) onDecisionAck(from string, a decisionAck) {
+	if op, ok := n.ops[a.Op]; ok {
+		delete(op.pending, from)
+	}
+}
+
+// resolve applies or drops a staged write and wakes the reads waiting for it.
+func (n *Node) resolve(key string, op opID, commit bool) {
+	n.finished[op] = true
+	s, ok := n.staged[key]
+	if !ok || s.Op != op {
+		return
+	}
+	delete(n.staged, key)
+	if commit {
+		n.apply(key, s.Version)
+	}
+	waiting := n.blocked[key]
+	delete(n.blocked, key)
+	for _, wake := range waiting {
+		wake()
+	}
+}
+
+// readSync answers from the local store once no write to key is in doubt.
+func (n *Node) readSync(key string, done func(string, error)) {
+	record := n.cluster.invoke(n.ID, linearizability.KVInput{Op: "get", Key: key})
+	reply := done
+	done = func(v string, err error) {
+		record(v, err)
+		reply(v, err)
+	}
+	answered := false
+	answer := func() {
+		if !answered {
+			answered = true
+			v, _ := n.Get(key)
+			done(v, nil)
+		}
+	}
+	if _, inDoubt := n.staged[key]; !inDoubt {
+		answer()
+		return
+	}
+	n.blocked[key] = append(n.blocked[key], answer)
+	n.cluster.net.After(n.cluster.Timeout, func() {
+		if !answered {
+			answered = true
+			done("", ErrInDoubt)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package capTheorem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+//Session guarantees
+//An AP store gives no guarantee across replicas, but a client can ask for guarantees about its own session (Terry et al., Bayou):
+//- read-your-writes: a read sees the writes the session made
+//- monotonic reads: a read sees at least what the session read before
+//- monotonic writes: a write is ordered after the writes the session made before
+//- writes-follow-reads: a write is ordered after the writes the session read
+//The session carries a token with the vector clocks of what it wrote and read, per key.
+//A replica can serve an operation only if what it holds covers the clocks the guarantees need; otherwise the session refuses it.
+//A session that sticks to one replica keeps every guarantee during a partition,
+//one that moves to the other side can only keep them by refusing operations until the replica catches up.
+//Guarantees are checked per key, like the vector clocks they rely on: ordering writes across keys is what the causal mode adds.
+
+const systemSession = "SimulateSessionGuarantees"
+
+// ErrSessionGuarantee is returned when a replica has not seen the writes a session guarantee needs.
+var ErrSessionGuarantee = errors.New("replica cannot provide the session guarantees")
+
+// Guarantee is a set of session guarantees.
+type Guarantee uint8
+
+const (
+	ReadYourWrites Guarantee = 1 << iota
+	MonotonicReads
+	MonotonicWrites
+	WritesFollowReads
+
+	// AllGuarantees is every session guarantee.
+	AllGuarantees = ReadYourWrites | MonotonicReads | MonotonicWrites | WritesFollowReads
+)
+
+var guaranteeNames = []string{"read-your-writes", "monotonic reads", "monotonic writes", "writes-follow-reads"}
+
+// Each returns the guarantees in g, one at a time.
+func (g Guarantee) Each() []Guarantee {
+	var out []Guarantee
+	for i := range guaranteeNames {
+		if one := Guarantee(1) << i; g&one != 0 {
+			out = append(out, one)
+		}
+	}
+	return out
+}
+
+func (g Guarantee) String() string {
+	if g == 0 {
+		return "none"
+	}
+	var names []string
+	for i, name := range guaranteeNames {
+		if g&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// SessionToken holds the clocks of the versions a session wrote and read, per key.
+// Clients keep it between requests, so any replica can check it.
+type SessionToken struct {
+	Writes map[string]VectorClock `json:"writes"`
+	Reads  map[string]VectorClock `json:"reads"`
+}
+
+// Session is a client of the AP replicas that tracks, and optionally enforces, the session guarantees.
+type Session struct {
+	c *Cluster
+	// Guarantees are enforced: operations a replica cannot serve without breaking them are refused
+	Guarantees Guarantee
+	Token      SessionToken
+	// Violated holds the guarantees that were not enforced and that an operation broke
+	Violated Guarantee
+	// Refused counts the operations each enforced guarantee refused
+	Refused map[Guarantee]int
+}
+
+// NewSession starts a session that enforces the guarantees g.
+func (c *Cluster) NewSession(g Guarantee) *Session {
+	return &Session{
+		c:          c,
+		Guarantees: g,
+		Token:      SessionToken{Writes: make(map[string]VectorClock), Reads: make(map[string]VectorClock)},
+		Refused:    make(map[Guarantee]int),
+	}
+}
+
+// Get reads key from the replica on nodeID, if it can serve the session.
+func (s *Session) Get(nodeID, key string) ([]Version, error) {
+	n := s.c.Node(nodeID)
+	if err := s.check(n, key, ReadYourWrites|MonotonicReads); err != nil {
+		return nil, err
+	}
+	versions := n.readLocal(key)
+	s.Token.Reads[key] = mergeContext(versions).Merge(s.Token.Reads[key])
+	return versions, nil
+}
+
+// Put writes key on nodeID, acknowledged by that replica only, if it can serve the session.
+// Like the AP clients, the write supersedes the versions the replica holds.
+func (s *Session) Put(nodeID, key, value string) error {
+	n := s.c.Node(nodeID)
+	if err := s.check(n, key, MonotonicWrites|WritesFollowReads); err != nil {
+		return err
+	}
+	v := n.newVersion(key, value, mergeContext(n.store[key]))
+	if err := s.c.await(func(done func(error)) { n.putVersion(key, v, 1, done) }); err != nil {
+		return err
+	}
+	s.Token.Writes[key] = v.Clock.Merge(s.Token.Writes[key])
+	return nil
+}
+
+// check reports whether n holds what the guarantees in needs require for key.
+// Enforced guarantees refuse the operation, the others are only recorded as violated.
+func (s *Session) check(n *Node, key string, needs Guarantee) error {
+	have := mergeContext(n.store[key])
+	var missing Guarantee
+	for _, g := range needs.Each() {
+		want := s.Token.Writes[key]
+		if g == MonotonicReads || g == WritesFollowReads {
+			want = s.Token.Reads[key]
+		}
+		if !have.Covers(want) {
+			missing |= g
+		}
+	}
+	if refused := missing & s.Guarantees; refused != 0 {
+		for _, g := range refused.Each() {
+			s.Refused[g]++
+		}
+		return fmt.Errorf("%w: %s has not seen what %s needs", ErrSessionGuarantee, n.ID, refused)
+	}
+	s.Violated |= missing
+	return nil
+}
+
+// Outcome describes what happened to guarantee g during the session.
+func (s *Session) Outcome(g Guarantee) string {
+	switch {
+	case s.Violated&g != 0:
+		return "violated"
+	case s.Refused[g] == 1:
+		return "kept by refusing 1 operation"
+	case s.Refused[g] > 1:
+		return fmt.Sprintf("kept by refusing %d operations", s.Refused[g])
+	}
+	return "held"
+}
+
+// SimulateSessionGuarantees runs the same session three times during a partition:
+// sticking to one replica, then moving to the other side without and with the guarantees enforced.
+func SimulateSessionGuarantees(ctx context.Context, cfg config.Network) error {
+	runs := []struct {
+		name       string
+		guarantees Guarantee
+		away       string
+	}{
+		{"sticky", AllGuarantees, "A"},
+		{"moving", 0, "C"},
+		{"moving and enforcing", AllGuarantees, "C"},
+	}
+	for _, run := range runs {
+		s, err := runSession(ctx, cfg, run.name, run.guarantees, run.away)
+		if err != nil {
+			return err
+		}
+		for _, g := range AllGuarantees.Each() {
+			events.Emit(ctx, events.Result{Name: fmt.Sprintf("%s %s %s", systemSession, run.name, g), Value: s.Outcome(g)})
+		}
+	}
+	return nil
+}
+
+// runSession writes and reads the cart on A, then does the same on away while C is cut off,
+// and reads it on away again once anti-entropy repaired the partition.
+func runSession(ctx context.Context, cfg config.Network, name string, g Guarantee, away string) (*Session, error) {
+	system := systemSession + " " + name
+	c := newCluster(cfg, "A", "B", "C")
+	c.StartAntiEntropy(500 * time.Millisecond)
+	partition(ctx, system, c, []string{"A", "B"}, []string{"C"})
+	events.Emit(ctx, events.Note{Text: fmt.Sprintf("%s: the session enforces %s", system, g)})
+
+	s := c.NewSession(g)
+	sessionPut(ctx, system, s, "A", "milk")
+	sessionGet(ctx, system, s, "A")
+	sessionGet(ctx, system, s, away)
+	sessionPut(ctx, system, s, away, "milk,bread")
+
+	if err := c.Run(ctx, time.Second); err != nil {
+		return nil, err
+	}
+	heal(ctx, system, c)
+	if err := c.Run(ctx, 2*time.Second); err != nil {
+		return nil, err
+	}
+	sessionGet(ctx, system, s, away)
+	return s, nil
+}
+
+func sessionPut(ctx context.Context, system string, s *Session, nodeID, newData string) error {
+	if err := s.Put(nodeID, dataKey, newData); err != nil {
+		events.Emit(ctx, events.WriteRejected{System: system, Node: nodeID, Value: newData, Reason: err.Error()})
+		return err
+	}
+	events.Emit(ctx, events.WriteAccepted{System: system, Node: nodeID, Value: newData})
+	return nil
+}
+
+func sessionGet(ctx context.Context, system string, s *Session, nodeID string) ([]Version, error) {
+	siblings, err := s.Get(nodeID, dataKey)
+	if err != nil {
+		events.Emit(ctx, events.ReadRejected{System: system, Node: nodeID, Reason: err.Error()})
+		return nil, err
+	}
+	if len(siblings) > 1 {
+		events.Emit(ctx, events.SiblingsFound{System: system, Node: nodeID, Key: dataKey, Siblings: siblingValues(siblings)})
+	} else {
+		events.Emit(ctx, events.ReadServed{System: system, Node: nodeID, Value: strings.Join(siblingValues(siblings), "")})
+	}
+	return siblings, nil
+}
@@ -0,0 +1,70 @@
+package capTheorem
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+)
+
+func TestSessionGuarantees(t *testing.T) {
+	tests := []struct {
+		name         string
+		enforce      Guarantee
+		away         string
+		wantViolated Guarantee
+		wantErr      error
+	}{
+		{"sticky", AllGuarantees, "A", 0, nil},
+		{"moving", 0, "C", ReadYourWrites | MonotonicReads, nil},
+		{"moving, writes are not reads", MonotonicWrites | WritesFollowReads, "C", ReadYourWrites | MonotonicReads, nil},
+		{"moving and enforcing", AllGuarantees, "C", 0, ErrSessionGuarantee},
+	}
+
+	for _, tt := range tests {
+		c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond}, "A", "B", "C")
+		c.Network().Partition([]string{"A", "B"}, []string{"C"})
+		s := c.NewSession(tt.enforce)
+		if err := s.Put("A", dataKey, "v1"); err != nil {
+			t.Fatalf("%s: put: %v", tt.name, err)
+		}
+		if _, err := s.Get("A", dataKey); err != nil {
+			t.Fatalf("%s: get: %v", tt.name, err)
+		}
+
+		if _, err := s.Get(tt.away, dataKey); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: read on %s = %v; want %v", tt.name, tt.away, err, tt.wantErr)
+		}
+		if s.Violated != tt.wantViolated {
+			t.Errorf("%s: violated %s; want %s", tt.name, s.Violated, tt.wantViolated)
+		}
+	}
+}
+
+func TestSessionGuaranteesAfterHeal(t *testing.T) {
+	c := newCluster(config.Network{Seed: 1, Latency: 10 * time.Millisecond}, "A", "B", "C")
+	c.StartAntiEntropy(100 * time.Millisecond)
+	c.Network().Partition([]string{"A", "B"}, []string{"C"})
+	s := c.NewSession(AllGuarantees)
+	if err := s.Put("A", dataKey, "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("C", dataKey, "v2"); !errors.Is(err, ErrSessionGuarantee) {
+		t.Fatalf("write on the other side = %v; want %v", err, ErrSessionGuarantee)
+	}
+
+	c.Network().Heal()
+	c.Network().RunFor(time.Second)
+	if err := s.Put("C", dataKey, "v2"); err != nil {
+		t.Fatalf("write once C caught up: %v", err)
+	}
+	c.Network().RunFor(100 * time.Millisecond)
+	versions, err := s.Get("B", dataKey)
+	if err != nil || len(versions) != 1 || versions[0].Value != "v2" {
+		t.Errorf("read on B = %v, %v; want v2 superseding v1", versions, err)
+	}
+	if got := s.Outcome(MonotonicWrites); got != "kept by refusing 1 operation" {
+		t.Errorf("monotonic writes %s", got)
+	}
+}
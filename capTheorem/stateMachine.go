@@ -0,0 +1,238 @@
+package capTheorem
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"GoBestPratices/capTheorem/linearizability"
+)
+
+//Replicated state machine
+//A consensus engine orders client commands into a log, and every node applies the log to its store in that order.
+//Since all replicas apply the same commands in the same order, they go through the same states,
+//and a command only completes once a majority stored it, so it survives the loss of any minority.
+//Commands carry an id: the node that submitted them and a sequence number.
+//Each node has a session on the state machine, holding only the last of its commands applied and the result:
+//a retried command is answered from it instead of being applied twice, and a command older than the last one
+//was overtaken, so it is not applied at all (Raft dissertation, 6.3). The sessions stay as small as the cluster.
+//A snapshot of the store and of the sessions can stand for every entry applied so far.
+
+// ErrNotLeader is returned when no leader can be reached to order a command.
+var ErrNotLeader = errors.New("no leader reachable")
+
+// ErrStaleCommand is returned when a later command of the same node was applied first.
+var ErrStaleCommand = errors.New("overtaken by a later command of the same node")
+
+// CommandID identifies a command by the node that submitted it and a sequence number.
+type CommandID struct {
+	Node string
+	Seq  uint64
+}
+
+// Command is an operation on the replicated key/value store: a put, a get or, with an empty Op, a no-op.
+type Command struct {
+	ID    CommandID
+	Op    string
+	Key   string
+	Value string
+}
+
+func (c Command) String() string {
+	switch c.Op {
+	case "put":
+		return fmt.Sprintf("put %s=%q", c.Key, c.Value)
+	case "get":
+		return "get " + c.Key
+	}
+	return "no-op"
+}
+
+// Replicator is a consensus engine running on one node.
+type Replicator interface {
+	// Propose submits cmd and calls done with its result once the node applied it, or with an error.
+	Propose(cmd Command, done func(result string, err error))
+	// Leader returns the node this one believes orders the commands, or "" if it knows none,
+	// and the term (or ballot) the node is in.
+	Leader() (id string, term uint64)
+	// receive handles the engine's messages.
+	receive(from string, payload any)
+	// restart resets the volatile state after the node crashed.
+	restart()
+}
+
+// pendingCommands holds the callbacks of the commands submitted on a node that have not completed.
+type pendingCommands map[CommandID]func(string, error)
+
+// add gives cmd a new id on n and registers done, failed with ErrUnavailable if cmd has not completed
+// after the cluster's timeout; leader tells whether a leader was known then.
+func (p pendingCommands) add(n *Node, cmd Command, done func(string, error), leader func() string) Command {
+	n.seq++
+	cmd.ID = CommandID{Node: n.ID, Seq: n.seq}
+	p[cmd.ID] = done
+	n.cluster.net.After(n.cluster.Timeout, func() {
+		if _, ok := p[cmd.ID]; !ok {
+			return
+		}
+		if leader() == "" {
+			p.complete(cmd.ID, "", fmt.Errorf("%w: %w", ErrUnavailable, ErrNotLeader))
+			return
+		}
+		p.complete(cmd.ID, "", fmt.Errorf("%w: %s not committed by a majority", ErrUnavailable, cmd))
+	})
+	return cmd
+}
+
+func (p pendingCommands) waiting(id CommandID) bool {
+	_, ok := p[id]
+	return ok
+}
+
+// fail completes every pending command with err, in the order they were submitted.
+func (p pendingCommands) fail(err error) {
+	ids := make([]CommandID, 0, len(p))
+	for id := range p {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b CommandID) int { return cmp.Compare(a.Seq, b.Seq) })
+	for _, id := range ids {
+		p.complete(id, "", err)
+	}
+}
+
+// completeRestored completes the pending commands a snapshot restored on n applied last for their node.
+// The snapshot cannot tell whether earlier ones were applied or overtaken: they are left to time out.
+func (p pendingCommands) completeRestored(n *Node) {
+	ids := make([]CommandID, 0, len(p))
+	for id := range p {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b CommandID) int { return cmp.Compare(a.Seq, b.Seq) })
+	for _, id := range ids {
+		if s := n.sessions[id.Node]; s.Seq == id.Seq {
+			p.complete(id, s.Result, nil)
+		}
+	}
+}
+
+func (p pendingCommands) complete(id CommandID, result string, err error) {
+	if done, ok := p[id]; ok {
+		delete(p, id)
+		done(result, err)
+	}
+}
+
+// StartConsensus runs the named consensus engine, raft or paxos, on every node of the cluster.
+func (c *Cluster) StartConsensus(engine string) error {
+	switch engine {
+	case "raft":
+		c.StartRaft()
+	case "paxos":
+		c.StartPaxos()
+	default:
+		return fmt.Errorf("unknown consensus engine %q, want raft or paxos", engine)
+	}
+	return nil
+}
+
+// CommandStats measures the commands clients submitted through consensus.
+type CommandStats struct {
+	Committed  int           `json:"committed"`
+	Failed     int           `json:"failed"`
+	Latency    time.Duration `json:"latency"`
+	MaxLatency time.Duration `json:"max_latency"`
+}
+
+func (s CommandStats) String() string {
+	var mean time.Duration
+	if s.Committed > 0 {
+		mean = s.Latency / time.Duration(s.Committed)
+	}
+	return fmt.Sprintf("committed=%d failed=%d mean latency=%v max latency=%v",
+		s.Committed, s.Failed, mean.Round(time.Millisecond), s.MaxLatency.Round(time.Millisecond))
+}
+
+// session is the last command of a node the state machine applied, and its result.
+type session struct {
+	Seq    uint64
+	Result string
+}
+
+// applied reports whether the state machine already applied the command id, or one of its node after it.
+func (n *Node) applied(id CommandID) bool {
+	return id.Seq <= n.sessions[id.Node].Seq
+}
+
+// applyCommand runs a committed command against the node's store and returns its result.
+// A command the node's session already saw is not applied again: a retry gets its result,
+// a command overtaken by a later one fails with ErrStaleCommand, on every replica alike.
+func (n *Node) applyCommand(cmd Command) (string, error) {
+	if cmd.Op == "" {
+		return "", nil
+	}
+	s := n.sessions[cmd.ID.Node]
+	switch {
+	case cmd.ID.Seq == s.Seq:
+		return s.Result, nil
+	case cmd.ID.Seq < s.Seq:
+		return "", notApplied{fmt.Errorf("%w: %s", ErrStaleCommand, cmd)}
+	}
+	var result string
+	switch cmd.Op {
+	case "put":
+		n.store[cmd.Key] = []Version{{Value: cmd.Value}}
+		result = cmd.Value
+	case "get":
+		result, _ = n.Get(cmd.Key)
+	}
+	n.sessions[cmd.ID.Node] = session{Seq: cmd.ID.Seq, Result: result}
+	return result, nil
+}
+
+// Snapshot is the state machine after applying the log up to Index, whose entry is of Term.
+// Paxos slots have no term: its snapshots leave Term at 0.
+type Snapshot struct {
+	Index    uint64
+	Term     uint64
+	Store    map[string][]Version
+	Sessions map[string]session
+}
+
+// snapshot copies the node's store and sessions.
+func (n *Node) snapshot(index, term uint64) Snapshot {
+	return Snapshot{Index: index, Term: term, Store: maps.Clone(n.store), Sessions: maps.Clone(n.sessions)}
+}
+
+// restore replaces the node's store and sessions with a copy of the snapshot's.
+func (n *Node) restore(s Snapshot) {
+	n.store = make(map[string][]Version, len(s.Store))
+	maps.Copy(n.store, s.Store)
+	n.sessions = make(map[string]session, len(s.Sessions))
+	maps.Copy(n.sessions, s.Sessions)
+}
+
+// propose submits a command on nodeID through its consensus engine and runs the simulation until it completes.
+func (c *Cluster) propose(nodeID string, cmd Command) (string, error) {
+	var result string
+	start := c.net.Elapsed()
+	record := c.invoke(nodeID, linearizability.KVInput{Op: cmd.Op, Key: cmd.Key, Value: cmd.Value})
+	err := c.await(func(done func(error)) {
+		c.Node(nodeID).consensus.Propose(cmd, func(r string, err error) {
+			result = r
+			done(err)
+		})
+	})
+	record(result, err)
+	if err != nil {
+		c.Commands.Failed++
+		return "", err
+	}
+	latency := c.net.Elapsed() - start
+	c.Commands.Committed++
+	c.Commands.Latency += latency
+	c.Commands.MaxLatency = max(c.Commands.MaxLatency, latency)
+	return result, nil
+}
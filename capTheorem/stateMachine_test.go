@@ -0,0 +1,157 @@
+package capTheorem
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+var engines = []string{"raft", "paxos"}
+
+// committed returns the commands a node knows are committed, the first one being at index first.
+func committed(n *Node) (first uint64, cmds []Command) {
+	switch e := n.consensus.(type) {
+	case *raft:
+		for i := e.snapIndex + 1; i <= e.commit; i++ {
+			cmds = append(cmds, e.entry(i).Cmd)
+		}
+		return e.snapIndex + 1, cmds
+	case *paxos:
+		return e.snapIndex + 1, e.log
+	}
+	return 1, nil
+}
+
+// checkLogs fails if two nodes committed different commands at the same index.
+func checkLogs(t *testing.T, c *Cluster) {
+	t.Helper()
+	for _, a := range c.Nodes() {
+		for _, b := range c.Nodes() {
+			fa, la := committed(a)
+			fb, lb := committed(b)
+			for i := max(fa, fb); i < min(fa+uint64(len(la)), fb+uint64(len(lb))); i++ {
+				if la[i-fa] != lb[i-fb] {
+					t.Fatalf("index %d committed as %v on %s and %v on %s", i, la[i-fa], a.ID, lb[i-fb], b.ID)
+				}
+			}
+		}
+	}
+}
+
+func TestConsensusReplication(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Network
+	}{
+		{"reliable", config.Network{Seed: 1, Latency: 10 * time.Millisecond}},
+		{"lossy", config.Network{Seed: 7, Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond, Drop: 0.2, Duplicate: 0.2, Reorder: 0.2}},
+	}
+
+	for _, engine := range engines {
+		for _, tt := range tests {
+			name := engine + " " + tt.name
+			c := newCluster(tt.cfg, "A", "B", "C", "D", "E")
+			c.Timeout = 2 * time.Second
+			c.SnapshotThreshold = 3 // Compact all the time
+			if err := c.StartConsensus(engine); err != nil {
+				t.Fatal(err)
+			}
+			for i, node := range []string{"A", "C", "E", "B"} {
+				value := string(rune('a' + i))
+				if _, err := c.propose(node, Command{Op: "put", Key: dataKey, Value: value}); err != nil {
+					t.Fatalf("%s: put %s on %s = %v", name, value, node, err)
+				}
+				if got, err := c.propose("D", Command{Op: "get", Key: dataKey}); err != nil || got != value {
+					t.Errorf("%s: get on D = %q, %v; want %q", name, got, err, value)
+				}
+			}
+			c.Network().RunFor(time.Second)
+			if c.Diverged(dataKey) {
+				t.Errorf("%s: replicas diverged: %v", name, c.Replicas(dataKey))
+			}
+			checkLogs(t, c)
+		}
+	}
+}
+
+func TestConsensusPartition(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine, func(t *testing.T) { testConsensusPartition(t, engine) })
+	}
+}
+
+func testConsensusPartition(t *testing.T, engine string) {
+	ctx := events.WithSink(context.Background(), events.Discard)
+	c := newCluster(config.Network{Seed: 3, Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond}, "A", "B", "C", "D", "E")
+	if err := c.StartConsensus(engine); err != nil {
+		t.Fatal(err)
+	}
+	old, err := awaitLeader(ctx, c, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The old leader ends up on the minority side with one follower
+	var majority, minority []string
+	for _, n := range c.Nodes() {
+		if n.ID != old && len(majority) < 3 {
+			majority = append(majority, n.ID)
+		} else {
+			minority = append(minority, n.ID)
+		}
+	}
+	c.Network().Partition(majority, minority)
+
+	for _, node := range minority {
+		if err := writeDataCP(ctx, c, node, "minority"); !errors.Is(err, ErrUnavailable) {
+			t.Errorf("write on minority node %s = %v; want ErrUnavailable", node, err)
+		}
+	}
+	if _, err := awaitLeader(ctx, c, old); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range majority {
+		if err := writeDataCP(ctx, c, node, "majority "+node); err != nil {
+			t.Errorf("write on majority node %s = %v", node, err)
+		}
+	}
+	if v, err := readDataCP(ctx, c, old); err == nil {
+		t.Errorf("read on the old leader during partition = %q; want an error", v)
+	}
+
+	c.Network().Heal()
+	want := "majority " + majority[2]
+	if v, err := readDataCP(ctx, c, old); err != nil || v != want {
+		t.Errorf("read on the old leader after heal = %q, %v; want %q", v, err, want)
+	}
+	c.Network().RunFor(time.Second)
+	for node, v := range c.Replicas(dataKey) {
+		if v != want {
+			t.Errorf("replica %s = %q; want %q", node, v, want)
+		}
+	}
+	checkLogs(t, c)
+}
+
+func TestApplyCommandSessions(t *testing.T) {
+	n := newCluster(config.Network{Seed: 1}, "A").Node("A")
+	put := func(seq uint64, value string) Command {
+		return Command{ID: CommandID{Node: "B", Seq: seq}, Op: "put", Key: dataKey, Value: value}
+	}
+
+	n.applyCommand(put(2, "v2"))
+	// A retry gets the result of the first application
+	if result, err := n.applyCommand(put(2, "v2")); err != nil || result != "v2" {
+		t.Errorf("retry = %q, %v; want the first result", result, err)
+	}
+	// An earlier command arriving last must not overwrite the later write
+	if _, err := n.applyCommand(put(1, "v1")); !errors.Is(err, ErrStaleCommand) {
+		t.Errorf("overtaken command = %v; want %v", err, ErrStaleCommand)
+	}
+	if v, _ := n.Get(dataKey); v != "v2" {
+		t.Errorf("%s = %q; want v2", dataKey, v)
+	}
+}
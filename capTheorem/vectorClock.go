@@ -0,0 +1,134 @@
+package capTheorem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//Vector clocks
+//A vector clock counts, for every node, how many writes of a key that node coordinated before this version.
+//If every counter of one clock is <= the other's, the first version happened before the second and can be dropped.
+//Otherwise the two writes were concurrent, typically on both sides of a partition, and both are kept as siblings
+//until a client reads them together and writes back a merged value.
+
+// VectorClock maps a node id to the number of writes it coordinated.
+type VectorClock map[string]uint64
+
+// Ordering is how two vector clocks relate.
+type Ordering int
+
+const (
+	Equal Ordering = iota
+	Before
+	After
+	Concurrent
+)
+
+func (o Ordering) String() string {
+	return [...]string{"equal", "before", "after", "concurrent"}[o]
+}
+
+// Compare reports whether v happened before, after or concurrently with o.
+func (v VectorClock) Compare(o VectorClock) Ordering {
+	less, greater := false, false
+	for node, n := range v {
+		if n > o[node] {
+			greater = true
+		}
+	}
+	for node, n := range o {
+		if n > v[node] {
+			less = true
+		}
+	}
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	}
+	return Equal
+}
+
+// Covers reports whether v has seen everything o has seen.
+func (v VectorClock) Covers(o VectorClock) bool {
+	ord := v.Compare(o)
+	return ord == After || ord == Equal
+}
+
+// Merge returns a clock that has seen everything v and o have seen.
+func (v VectorClock) Merge(o VectorClock) VectorClock {
+	out := v.Copy()
+	for node, n := range o {
+		if n > out[node] {
+			out[node] = n
+		}
+	}
+	return out
+}
+
+// Copy returns a clock that can be changed without affecting v.
+func (v VectorClock) Copy() VectorClock {
+	out := make(VectorClock, len(v))
+	for node, n := range v {
+		out[node] = n
+	}
+	return out
+}
+
+func (v VectorClock) String() string {
+	nodes := make([]string, 0, len(v))
+	for node := range v {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = fmt.Sprintf("%s:%d", node, v[node])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// Version is one value of a key with the clock of the write that produced it.
+type Version struct {
+	Value string
+	Clock VectorClock
+}
+
+// addVersion merges v into siblings: versions v supersedes are dropped,
+// and v is ignored if a sibling already supersedes or equals it.
+func addVersion(siblings []Version, v Version) []Version {
+	out := make([]Version, 0, len(siblings)+1)
+	for _, s := range siblings {
+		switch v.Clock.Compare(s.Clock) {
+		case Before, Equal:
+			return siblings
+		case Concurrent:
+			out = append(out, s)
+		}
+	}
+	out = append(out, v)
+	// Keep siblings in a stable order so runs are reproducible
+	sort.Slice(out, func(i, j int) bool { return out[i].Clock.String() < out[j].Clock.String() })
+	return out
+}
+
+// mergeContext returns the clock that supersedes every sibling, the context of a write that resolves them.
+func mergeContext(siblings []Version) VectorClock {
+	clock := VectorClock{}
+	for _, s := range siblings {
+		clock = clock.Merge(s.Clock)
+	}
+	return clock
+}
+
+func siblingValues(siblings []Version) []string {
+	values := make([]string, len(siblings))
+	for i, s := range siblings {
+		values[i] = s.Value
+	}
+	return values
+}
@@ -0,0 +1,77 @@
+package capTheorem
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+)
+
+func TestVectorClockCompare(t *testing.T) {
+	tests := []struct {
+		a, b VectorClock
+		want Ordering
+	}{
+		{VectorClock{}, VectorClock{}, Equal},
+		{VectorClock{"A": 1}, VectorClock{"A": 1}, Equal},
+		{VectorClock{"A": 1}, VectorClock{"A": 2}, Before},
+		{VectorClock{"A": 1, "B": 1}, VectorClock{"A": 1}, After},
+		{VectorClock{"A": 2}, VectorClock{"A": 1, "C": 1}, Concurrent},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%v.Compare(%v) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAddVersion(t *testing.T) {
+	base := Version{Value: "milk", Clock: VectorClock{"A": 1}}
+	left := Version{Value: "milk,eggs", Clock: VectorClock{"A": 2}}
+	right := Version{Value: "milk,bread", Clock: VectorClock{"A": 1, "C": 1}}
+	merged := Version{Value: "bread,eggs,milk", Clock: VectorClock{"A": 3, "C": 1}}
+
+	siblings := addVersion(nil, base)
+	siblings = addVersion(siblings, left)
+	siblings = addVersion(siblings, right)
+	siblings = addVersion(siblings, base) // stale redelivery
+	if got, want := siblingValues(siblings), []string{"milk,bread", "milk,eggs"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("siblings = %v; want %v", got, want)
+	}
+	if got := mergeContext(siblings); got.Compare(merged.Clock) != Before {
+		t.Errorf("mergeContext = %v; want a clock before %v", got, merged.Clock)
+	}
+	if got := addVersion(siblings, merged); len(got) != 1 || got[0].Value != merged.Value {
+		t.Errorf("merged write left siblings %v", siblingValues(got))
+	}
+}
+
+func TestAPSiblingsAfterPartition(t *testing.T) {
+	ctx := events.WithSink(context.Background(), events.Discard)
+	c := newCluster(config.Network{Seed: 1}, "A", "B", "C")
+	writeDataAP(ctx, c, "A", "milk", nil)
+	c.Network().RunFor(c.Timeout)
+
+	c.Network().Partition([]string{"A", "B"}, []string{"C"})
+	_, seen := readDataAP(ctx, c, "A", 1)
+	writeDataAP(ctx, c, "A", "milk,eggs", seen)
+	_, seen = readDataAP(ctx, c, "C", 1)
+	writeDataAP(ctx, c, "C", "milk,bread", seen)
+	c.Network().RunFor(c.Timeout)
+	c.Network().Heal()
+
+	siblings, _ := readDataAP(ctx, c, "B", 3)
+	if len(siblings) != 2 {
+		t.Fatalf("read after heal = %v; want both carts as siblings", siblingValues(siblings))
+	}
+	if err := resolveAP(ctx, c, "B", unionResolver); err != nil {
+		t.Fatal(err)
+	}
+	for node, v := range c.Replicas(dataKey) {
+		if v != "bread,eggs,milk" {
+			t.Errorf("replica %s = %q after resolving; want the merged cart", node, v)
+		}
+	}
+}
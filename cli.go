@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"GoBestPratices/clock"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+	"GoBestPratices/logging"
+	"GoBestPratices/registry"
+	"GoBestPratices/server"
+	"GoBestPratices/tracing"
+)
+
+// Exit codes returned by the command line, so scripts can tell a failing demo from a typo.
+const (
+	exitOK      = 0 // Every requested demo finished successfully
+	exitFailure = 1 // At least one demo returned an error
+	exitUsage   = 2 // Unknown subcommand, unknown demo or invalid flags
+
+	exitInterrupted = 130 // Stopped by SIGINT or SIGTERM, like a shell reports 128+SIGINT
+)
+
+const usage = `Usage:
+  GoBestPratices list                     List the available demos
+  GoBestPratices run <name> [flags]       Run a single demo
+  GoBestPratices run --all                Run every demo in order
+  GoBestPratices run <name> -h            Show the flags of a demo
+  GoBestPratices serve [-addr host:port]  Serve the demos over HTTP with Server-Sent Events
+  GoBestPratices config                   Print the effective configuration
+
+Run flags (before the demo name):
+  -output text|json                       Render demo events as text or JSON lines
+  -trace-file spans.jsonl                 Write the trace spans of the run to a JSON lines file
+
+Configuration flags (run, serve and config):
+  -config file.json                       Load a configuration file (default $GOBP_CONFIG)
+  -set key=value                          Override a configuration key, repeatable
+`
+
+// runCLI dispatches the subcommand and returns the process exit code.
+// Cancelling ctx stops the running demos and the server gracefully.
+func runCLI(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return listDemos(stdout)
+	case "run":
+		return runDemos(ctx, args[1:], stdout, stderr)
+	case "serve":
+		return serve(ctx, args[1:], stdout, stderr)
+	case "config":
+		return printConfig(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+func listDemos(stdout io.Writer) int {
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCATEGORY\tDESCRIPTION")
+	for _, e := range registry.All() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Name, e.Category, e.Description)
+	}
+	if err := w.Flush(); err != nil {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runDemos(parent context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	all := fs.Bool("all", false, "run every demo with its default flags")
+	output := fs.String("output", "text", "render demo events as text or json")
+	traceFile := fs.String("trace-file", "", "write trace spans as JSON lines to this file")
+	var cf configFlags
+	cf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cfg, err := config.Load(cf.path, os.Environ(), cf.overrides)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	sink, err := newSink(*output, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	ctx := events.WithSink(newContext(parent, cfg, stderr), sink)
+
+	if *traceFile != "" {
+		exp, err := tracing.NewJSONFileExporter(*traceFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		defer func() {
+			if err := exp.Close(); err != nil {
+				logging.FromContext(ctx).Error("writing trace file", "err", err)
+			}
+		}()
+		ctx = tracing.WithTracer(ctx, tracing.NewTracer(exp))
+	}
+
+	if *all {
+		if fs.NArg() > 0 {
+			fmt.Fprintln(stderr, "run --all does not take a demo name")
+			return exitUsage
+		}
+		var planned []plannedRun
+		for _, e := range registry.All() {
+			e = cfg.Apply(e)
+			args, err := e.Parse(nil)
+			if err == nil {
+				err = cfg.ValidateArgs(e, args)
+			}
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return exitUsage
+			}
+			planned = append(planned, plannedRun{example: e, args: args})
+		}
+		return runPlanned(ctx, planned, true)
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	e, ok := registry.Lookup(fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(stderr, "unknown demo %q, use \"list\" to see the available demos\n", fs.Arg(0))
+		return exitUsage
+	}
+	e = cfg.Apply(e)
+
+	// Each demo owns its flags, parsed after the demo name
+	demoFlags := flag.NewFlagSet(e.Name, flag.ContinueOnError)
+	demoFlags.SetOutput(stderr)
+	values := make(map[string]string)
+	for _, p := range e.Params {
+		usage := p.Usage
+		if len(p.Choices) > 0 {
+			usage += ": " + strings.Join(p.Choices, ", ")
+		}
+		demoFlags.Var(&paramFlag{param: p, values: values}, p.Name, usage)
+	}
+	if err := demoFlags.Parse(fs.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if demoFlags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments %v\n", demoFlags.Args())
+		return exitUsage
+	}
+
+	demoArgs, err := e.Parse(values)
+	if err == nil {
+		err = cfg.ValidateArgs(e, demoArgs)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	return runPlanned(ctx, []plannedRun{{example: e, args: demoArgs}}, false)
+}
+
+// plannedRun is a demo with its validated arguments
+type plannedRun struct {
+	example registry.Example
+	args    registry.Args
+}
+
+// runPlanned runs the demos in order and keeps going after a failure. Once ctx is
+// cancelled the demo in flight winds down, the remaining ones are skipped, and
+// a summary is emitted either way.
+func runPlanned(ctx context.Context, planned []plannedRun, announce bool) int {
+	clk := clock.FromContext(ctx)
+	start := clk.Now()
+	var summary events.RunSummary
+
+	for _, p := range planned {
+		if ctx.Err() != nil {
+			summary.Cancelled = append(summary.Cancelled, p.example.Name)
+			continue
+		}
+		if announce {
+			events.Emit(ctx, events.Note{Text: "=== " + p.example.Name})
+		}
+
+		demoCtx := logging.With(ctx, "demo", p.example.Name)
+		err := p.example.Run(demoCtx, p.args)
+		switch {
+		case err == nil:
+			summary.Succeeded = append(summary.Succeeded, p.example.Name)
+		case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+			logging.FromContext(demoCtx).Warn("demo cancelled")
+			summary.Cancelled = append(summary.Cancelled, p.example.Name)
+		default:
+			logging.FromContext(demoCtx).Error("demo failed", "err", err)
+			summary.Failed = append(summary.Failed, p.example.Name)
+		}
+	}
+
+	summary.Elapsed = clk.Since(start)
+	events.Emit(ctx, summary)
+
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case len(summary.Failed) > 0:
+		return exitFailure
+	default:
+		return exitOK
+	}
+}
+
+func serve(parent context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	var cf configFlags
+	cf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cfg, err := config.Load(cf.path, os.Environ(), cf.overrides)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	ctx := newContext(parent, cfg, stderr)
+	logging.FromContext(ctx).Info("serving demos", "url", "http://"+*addr+"/demos")
+	if err := server.New(ctx, cfg).ListenAndServe(ctx, *addr); err != nil {
+		logging.FromContext(ctx).Error("server stopped", "err", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func printConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cf configFlags
+	cf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cfg, err := config.Load(cf.path, os.Environ(), cf.overrides)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tENVIRONMENT")
+	for _, key := range cfg.Keys() {
+		value, _ := cfg.Get(key)
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, config.EnvVar(key))
+	}
+	if err := w.Flush(); err != nil {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// newContext returns the context of a command, carrying the configured logger
+func newContext(parent context.Context, cfg config.Config, stderr io.Writer) context.Context {
+	// config.Load already validated the level and the format
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logger, err := logging.New(stderr, cfg.Log.Format, level)
+	if err != nil {
+		logger = slog.New(slog.NewTextHandler(stderr, nil))
+	}
+	return logging.WithLogger(parent, logger)
+}
+
+// configFlags are the configuration flags shared by run, serve and config
+type configFlags struct {
+	path      string
+	overrides stringList
+}
+
+func (c *configFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.path, "config", os.Getenv(config.EnvPrefix+"CONFIG"), "JSON configuration file")
+	fs.Var(&c.overrides, "set", "override a configuration key as key=value (repeatable)")
+}
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// newSink returns the renderer selected by the -output flag
+func newSink(format string, w io.Writer) (events.Sink, error) {
+	switch format {
+	case "text":
+		return events.NewTextSink(w), nil
+	case "json":
+		return events.NewJSONSink(w), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, want text or json", format)
+	}
+}
+
+// paramFlag collects a demo parameter from the command line.
+// Values are validated by registry.Example.Parse once every flag is read.
+type paramFlag struct {
+	param  registry.Param
+	values map[string]string
+}
+
+func (f *paramFlag) String() string {
+	if f == nil || f.values == nil {
+		return ""
+	}
+	if v, ok := f.values[f.param.Name]; ok {
+		return v
+	}
+	return f.param.Default
+}
+
+func (f *paramFlag) Set(v string) error {
+	f.values[f.param.Name] = v
+	return nil
+}
+
+// IsBoolFlag lets boolean parameters be passed as -name without a value
+func (f *paramFlag) IsBoolFlag() bool {
+	return f.param.Type == registry.Bool
+}
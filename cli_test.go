@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"GoBestPratices/config"
+	"GoBestPratices/registry"
+)
+
+func TestEveryDemoIsRegistered(t *testing.T) {
+	names := []string{
+		"goroutine", "channel", "channel-goroutine", "mutex",
+		"cap-cp", "cap-ap", "cap-ca",
+		"two-phase-commit", "redis-cashback",
+		"circuit-breaker",
+		"web-crawler", "context", "error-handling", "optimizing",
+	}
+
+	for _, name := range names {
+		e, ok := registry.Lookup(name)
+		if !ok {
+			t.Errorf("demo %q is not registered", name)
+			continue
+		}
+		if e.Category == "" || e.Description == "" {
+			t.Errorf("demo %q is missing its category or description", name)
+		}
+	}
+}
+
+// The defaults in each demo schema document the configuration defaults; keep them in sync
+func TestParamDefaultsMatchConfig(t *testing.T) {
+	cfg := config.Default()
+	for _, e := range registry.All() {
+		for _, p := range e.Params {
+			if p.Key == "" {
+				continue
+			}
+			want, ok := cfg.Get(p.Key)
+			if !ok {
+				t.Errorf("%s -%s is bound to unknown config key %q", e.Name, p.Name, p.Key)
+				continue
+			}
+			if p.Default != want {
+				t.Errorf("%s -%s default = %q; config %s = %q", e.Name, p.Name, p.Default, p.Key, want)
+			}
+		}
+	}
+}
+
+func TestRunCLIExitCodes(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{}, exitUsage},
+		{[]string{"list"}, exitOK},
+		{[]string{"unknown"}, exitUsage},
+		{[]string{"run", "unknown"}, exitUsage},
+		{[]string{"run", "error-handling"}, exitOK},
+		{[]string{"run", "two-phase-commit", "-amount", "nope"}, exitUsage},
+		{[]string{"run", "two-phase-commit", "-amount", "5000"}, exitFailure},
+		{[]string{"run", "cap-cp", "-engine", "pax"}, exitUsage},
+		{[]string{"run", "circuit-breaker", "-attempts", "0"}, exitUsage},
+	}
+
+	for _, tt := range tests {
+		if got := runCLI(context.Background(), tt.args, io.Discard, io.Discard); got != tt.want {
+			t.Errorf("runCLI(%v) = %d; want %d", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunCLIInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The worker sleeps 5s on the real clock; cancellation must not wait for it
+	if got := runCLI(ctx, []string{"run", "channel-goroutine"}, io.Discard, io.Discard); got != exitInterrupted {
+		t.Errorf("runCLI with a cancelled context = %d; want %d", got, exitInterrupted)
+	}
+}
@@ -0,0 +1,105 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+//Time-dependent code should not call time.Now or time.Sleep directly.
+//Asking a Clock instead lets a test swap the real clock for a Fake one and drive
+//every sleep and timeout instantly and reproducibly.
+//Like the event sink, the clock travels in the context of a demo run.
+
+// Clock is the subset of the time package the demos depend on.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	// NewTimer is After with a stop function, which releases the timer and reports whether it had not fired yet.
+	NewTimer(d time.Duration) (<-chan time.Time, func() bool)
+}
+
+// Real is the wall clock backed by the time package.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	t := time.NewTimer(d)
+	return t.C, t.Stop
+}
+
+type clockKey struct{}
+
+// WithClock returns a context whose time-dependent code uses c.
+func WithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// FromContext returns the clock carried by ctx, or Real.
+func FromContext(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return c
+	}
+	return Real
+}
+
+// Sleep pauses for d on the clock carried by ctx.
+// It returns ctx.Err() early when ctx is cancelled, so long-running demos stop promptly.
+func Sleep(ctx context.Context, d time.Duration) error {
+	fired, stop := FromContext(ctx).NewTimer(d)
+	select {
+	case <-fired:
+		return nil
+	case <-ctx.Done():
+		stop()
+		return ctx.Err()
+	}
+}
+
+// WithTimeout is context.WithTimeout measured on the clock carried by ctx,
+// so a Fake clock can expire the deadline without waiting.
+func WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	c := FromContext(ctx)
+	if c == Real {
+		return context.WithTimeout(ctx, d)
+	}
+
+	inner, cancel := context.WithCancelCause(ctx)
+	fired, stop := c.NewTimer(d)
+	go func() {
+		select {
+		case <-fired:
+			cancel(context.DeadlineExceeded)
+		case <-inner.Done():
+			stop()
+		}
+	}()
+	return &deadlineCtx{Context: inner, deadline: c.Now().Add(d)}, func() { cancel(context.Canceled) }
+}
+
+// deadlineCtx reports context.DeadlineExceeded as Err once the fake deadline
+// expired, so it looks exactly like a context.WithTimeout to callers.
+type deadlineCtx struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *deadlineCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *deadlineCtx) Err() error {
+	err := c.Context.Err()
+	if err != nil && errors.Is(context.Cause(c.Context), context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeAdvanceFiresWaitersInOrder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	late := f.After(2 * time.Second)
+	early := f.After(time.Second)
+
+	f.Advance(time.Second)
+	select {
+	case got := <-early:
+		if !got.Equal(start.Add(time.Second)) {
+			t.Errorf("early fired at %v; want %v", got, start.Add(time.Second))
+		}
+	default:
+		t.Fatal("early waiter did not fire after 1s")
+	}
+	if f.Waiters() != 1 {
+		t.Fatalf("Waiters() = %d; want 1", f.Waiters())
+	}
+
+	f.Advance(time.Second)
+	select {
+	case <-late:
+	default:
+		t.Fatal("late waiter did not fire after 2s")
+	}
+}
+
+func TestWithTimeoutOnFakeClock(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	ctx, cancel := WithTimeout(WithClock(context.Background(), f), time.Second)
+	defer cancel()
+
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+	<-ctx.Done()
+
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("ctx.Err() = %v; want %v", ctx.Err(), context.DeadlineExceeded)
+	}
+}
+
+func TestWithTimeoutErrIsStandardSentinel(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	parent, cancelParent := context.WithCancelCause(WithClock(context.Background(), f))
+	ctx, cancel := WithTimeout(parent, time.Second)
+	defer cancel()
+
+	cancelParent(errors.New("shutting down"))
+	<-ctx.Done()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("ctx.Err() = %v after the parent was cancelled; want %v", ctx.Err(), context.Canceled)
+	}
+}
+
+func TestCancelledSleepReleasesWaiter(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	ctx, cancel := context.WithCancel(WithClock(context.Background(), f))
+	done := make(chan error)
+	go func() { done <- Sleep(ctx, time.Second) }()
+
+	f.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Sleep = %v; want %v", err, context.Canceled)
+	}
+	if f.Waiters() != 0 {
+		t.Errorf("Waiters() = %d after the sleep was cancelled; want 0", f.Waiters())
+	}
+}
+
+func TestCancelledTimeoutReleasesWaiter(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	_, cancel := WithTimeout(WithClock(context.Background(), f), time.Second)
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for f.Waiters() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Waiters() = %d after the timeout was cancelled; want 0", f.Waiters())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
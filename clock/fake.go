@@ -0,0 +1,125 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a virtual clock that only moves when Advance is called.
+// Sleep, After and NewTimer block until the clock is advanced past their deadline.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	changed chan struct{} // closed and replaced whenever the waiters change
+}
+
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFake returns a fake clock set to start.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start, changed: make(chan struct{})}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	ch, _ := f.NewTimer(d)
+	return ch
+}
+
+// NewTimer is After with a stop function that removes the waiter from the clock if it has not fired yet.
+func (f *Fake) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch, func() bool { return false }
+	}
+	w := &waiter{deadline: f.now.Add(d), ch: ch}
+	f.waiters = append(f.waiters, w)
+	f.notify()
+	return ch, func() bool { return f.stop(w) }
+}
+
+// stop removes w from the waiters and reports whether it was still waiting.
+func (f *Fake) stop(w *waiter) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// Advance moves the clock forward and fires every waiter whose deadline has passed, earliest first.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+	})
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- f.now
+	}
+	f.waiters = pending
+	f.notify()
+}
+
+// Waiters returns how many Sleep or After calls are waiting on the clock.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil waits until at least n goroutines are waiting on the clock.
+// Tests call it before Advance so that the code under test reached its sleep.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		if len(f.waiters) >= n {
+			f.mu.Unlock()
+			return
+		}
+		changed := f.changed
+		f.mu.Unlock()
+		<-changed
+	}
+}
+
+// notify wakes BlockUntil callers; f.mu must be held
+func (f *Fake) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
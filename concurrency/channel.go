@@ -1,9 +1,14 @@
 package concurrency
 
 import (
-	"fmt"
+	"context"
+	"strconv"
 	"sync"
 	"time"
+
+	"GoBestPratices/clock"
+	"GoBestPratices/events"
+	"GoBestPratices/logging"
 )
 
 // Worker pool pattern for processing jobs efficiently.
@@ -17,18 +22,26 @@ import (
 //Concurrency: Safe for goroutines, blocks when full/empty
 //Blocking Behavior: Sender blocks when full, receiver blocks when empty
 
-func RunChannel() {
+// RunChannel stops feeding the pool when ctx is cancelled; the workers then
+// drain the queued jobs without processing them, so no goroutine is left blocked.
+func RunChannel(ctx context.Context) error {
 	jobs := make(chan int, 5)
 	results := make(chan int, 5)
 	var wg sync.WaitGroup
 
 	for w := 1; w <= 3; w++ {
 		wg.Add(1)
-		go worker(w, jobs, results, &wg)
+		go worker(ctx, w, jobs, results, &wg)
 	}
 
+feed:
 	for j := 1; j <= 5; j++ {
-		jobs <- j
+		select {
+		case jobs <- j:
+			queueDepth.Set(float64(len(jobs)))
+		case <-ctx.Done():
+			break feed
+		}
 	}
 	close(jobs)
 
@@ -36,28 +49,44 @@ func RunChannel() {
 	close(results) // Prevents workers from waiting indefinitely
 
 	for res := range results {
-		fmt.Println("Result:", res)
+		events.Emit(ctx, events.Result{Name: "Result", Value: res})
 	}
+	return ctx.Err()
 }
 
-func worker(id int, jobs <-chan int, results chan<- int, wg *sync.WaitGroup) {
+func worker(ctx context.Context, id int, jobs <-chan int, results chan<- int, wg *sync.WaitGroup) {
 	defer wg.Done()
+	ctx = logging.With(ctx, "worker", id)
 	for job := range jobs {
-		fmt.Printf("Worker %d processing job %d\n", id, job)
+		queueDepth.Set(float64(len(jobs)))
+		if ctx.Err() != nil {
+			logging.FromContext(ctx).Debug("dropping job after cancellation", "job", job)
+			continue
+		}
+		jobsProcessed.Inc(strconv.Itoa(id))
+		logging.FromContext(ctx).Debug("processing job", "job", job)
+		events.Emit(ctx, events.JobProcessed{Worker: id, Job: job, Result: job * 2})
 		results <- job * 2
 	}
 }
 
-func ChannelGoRoutine() {
+func ChannelGoRoutine(ctx context.Context) error {
 	ch := make(chan string, 1) // Buffered channel with capacity 1
 
-	go workerGoRoutine(ch) // Start goroutine
+	go workerGoRoutine(ctx, ch) // Start goroutine
 
-	fmt.Println("Waiting for worker...")
-	msg := <-ch // Receive message (blocks if empty)
-	fmt.Println(msg)
+	events.Emit(ctx, events.Note{Text: "Waiting for worker..."})
+	select {
+	case msg := <-ch: // Receive message (blocks if empty)
+		events.Emit(ctx, events.Result{Name: "Message", Value: msg})
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
-func workerGoRoutine(ch chan string) {
-	time.Sleep(5 * time.Second)
-	ch <- "Task done!" // Send message to channel
+func workerGoRoutine(ctx context.Context, ch chan string) {
+	if clock.Sleep(ctx, 5*time.Second) != nil {
+		return
+	}
+	ch <- "Task done!" // Send message to channel, never blocks thanks to the buffer
 }
@@ -1,20 +1,22 @@
 package concurrency
 
 import (
-	"fmt"
+	"context"
 	"sync"
+
+	"GoBestPratices/events"
 )
 
 // Goroutines are lightweight, but excessive spawning can lead to high memory usage.
 // Use sync.WaitGroup to manage goroutines efficiently.
 // Key concept: Avoid common pitfalls like capturing loop variables incorrectly in goroutines.
-func GoRoutine() {
+func GoRoutine(ctx context.Context) {
 	var wg sync.WaitGroup
 	for i := 0; i < 10; i++ {
 		wg.Add(1)
 		go func(i int) { //<- Capture i correctly
 			defer wg.Done()
-			fmt.Println("Goroutine", i)
+			events.Emit(ctx, events.TaskCompleted{Task: i})
 		}(i) // Pass i as argument
 	}
 	wg.Wait()
@@ -0,0 +1,9 @@
+package concurrency
+
+import "GoBestPratices/metrics"
+
+// Worker pool metrics, exposed on /metrics
+var (
+	jobsProcessed = metrics.NewCounter("worker_pool_jobs_processed_total", "Jobs processed by the worker pool, per worker.", "worker")
+	queueDepth    = metrics.NewGauge("worker_pool_queue_depth", "Jobs waiting in the worker pool queue.")
+)
@@ -1,8 +1,10 @@
 package concurrency
 
 import (
-	"fmt"
+	"context"
 	"sync"
+
+	"GoBestPratices/events"
 )
 
 var (
@@ -10,7 +12,7 @@ var (
 	mu      sync.Mutex // Mutex to protect the shared counter
 )
 
-func Mutex() {
+func Mutex(ctx context.Context) {
 	var wg sync.WaitGroup
 
 	// Launch 10 goroutines to increment the counter concurrently
@@ -26,7 +28,7 @@ func Mutex() {
 	wg.Wait()
 
 	// Print the result after all increments
-	fmt.Println("Final Counter:", counter)
+	events.Emit(ctx, events.Result{Name: "Final Counter", Value: counter})
 }
 
 func increment() {
@@ -0,0 +1,44 @@
+package concurrency
+
+import (
+	"context"
+
+	"GoBestPratices/registry"
+)
+
+func init() {
+	registry.Register(registry.Example{
+		Name:        "goroutine",
+		Category:    "concurrency",
+		Description: "Launch goroutines tracked by a WaitGroup",
+		Run: func(ctx context.Context, args registry.Args) error {
+			GoRoutine(ctx)
+			return nil
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "channel",
+		Category:    "concurrency",
+		Description: "Worker pool over buffered channels",
+		Run: func(ctx context.Context, args registry.Args) error {
+			return RunChannel(ctx)
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "channel-goroutine",
+		Category:    "concurrency",
+		Description: "Wait for a worker on a buffered channel",
+		Run: func(ctx context.Context, args registry.Args) error {
+			return ChannelGoRoutine(ctx)
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "mutex",
+		Category:    "concurrency",
+		Description: "Protect a shared counter with sync.Mutex",
+		Run: func(ctx context.Context, args registry.Args) error {
+			Mutex(ctx)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoBestPratices/logging"
+	"GoBestPratices/registry"
+)
+
+//Configuration is layered, each layer overriding the previous one:
+//1. Defaults (the values the demos used to hard-code)
+//2. A JSON file, see config/example.json
+//3. Environment variables, GOBP_ followed by the key in upper case: GOBP_CRAWLER_DEPTH=2
+//4. Command line flags: -set crawler.depth=2, or the flags of a demo (-depth 2)
+//Every value has a dotted key made of the json tags of its section and field, e.g. "redis.addr".
+
+// EnvPrefix is the prefix of the environment variables read by ApplyEnv.
+const EnvPrefix = "GOBP_"
+
+type Config struct {
+	Log      Log      `json:"log"`
+	Crawler  Crawler  `json:"crawler"`
+	Redis    Redis    `json:"redis"`
+	Breaker  Breaker  `json:"breaker"`
+	Payment  Payment  `json:"payment"`
+	Network  Network  `json:"network"`
+	Detector Detector `json:"detector"`
+}
+
+// Log configures the shared logger, see logging.New.
+type Log struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+// Crawler configures pratices.RunWebCrawler.
+type Crawler struct {
+	StartURL string `json:"start_url"`
+	Depth    int    `json:"depth"`
+}
+
+// Redis configures consistency.ProcessWithRedis.
+type Redis struct {
+	Addr        string `json:"addr"`
+	DB          int    `json:"db"`
+	BudgetLimit int    `json:"budget_limit"`
+}
+
+// Breaker configures resilience.GoCircuitBreake.
+type Breaker struct {
+	Name             string        `json:"name"`
+	MaxRequests      uint32        `json:"max_requests"`
+	Interval         time.Duration `json:"interval"`
+	Timeout          time.Duration `json:"timeout"`
+	FailureThreshold uint32        `json:"failure_threshold"`
+	Attempts         int           `json:"attempts"`
+}
+
+// Payment configures consistency.TwoPhaseCommit.
+type Payment struct {
+	Balance int `json:"balance"`
+	Amount  int `json:"amount"`
+}
+
+// Network configures the simulated network of the capTheorem demos.
+// Drop, Duplicate and Reorder are probabilities per message.
+type Network struct {
+	Seed      int           `json:"seed"`
+	Latency   time.Duration `json:"latency"`
+	Jitter    time.Duration `json:"jitter"`
+	Drop      float64       `json:"drop"`
+	Duplicate float64       `json:"duplicate"`
+	Reorder   float64       `json:"reorder"`
+}
+
+// Detector configures the phi-accrual failure detector of the capTheorem nodes.
+// A node suspects a peer once phi, computed from the gaps between the peer's heartbeats, reaches Threshold.
+type Detector struct {
+	Threshold       float64       `json:"threshold"`
+	Heartbeat       time.Duration `json:"heartbeat"`
+	MinStdDev       time.Duration `json:"min_stddev"`
+	AcceptablePause time.Duration `json:"acceptable_pause"`
+	Window          int           `json:"window"`
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() Config {
+	return Config{
+		Log:     Log{Level: "info", Format: "text"},
+		Crawler: Crawler{StartURL: "https://www.example.ai", Depth: 3},
+		Redis:   Redis{Addr: "localhost:6379", DB: 0, BudgetLimit: 10000},
+		Breaker: Breaker{
+			Name:             "ExampleCircuitBreaker",
+			MaxRequests:      5,
+			Interval:         60 * time.Second,
+			Timeout:          10 * time.Second,
+			FailureThreshold: 2,
+			Attempts:         10,
+		},
+		Payment:  Payment{Balance: 1000, Amount: 500},
+		Network:  Network{Seed: 1, Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond},
+		Detector: Detector{Threshold: 8, Heartbeat: 50 * time.Millisecond, MinStdDev: 10 * time.Millisecond, Window: 50},
+	}
+}
+
+// Load builds the configuration from every layer and validates it.
+// path may be empty, overrides are "key=value" pairs from the command line.
+func Load(path string, environ []string, overrides []string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.ApplyEnv(environ); err != nil {
+		return Config{}, err
+	}
+	for _, o := range overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("invalid override %q, want key=value", o)
+		}
+		if err := cfg.Set(key, value); err != nil {
+			return Config{}, err
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// LoadFile applies a JSON file of sections, for example {"crawler": {"depth": 2}}.
+// Durations are written as strings such as "10s"; unknown keys are rejected.
+func (c *Config) LoadFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	var sections map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &sections); err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	for section, fields := range sections {
+		for field, value := range fields {
+			// Strings lose their quotes, numbers and booleans keep their literal form
+			v := string(value)
+			var s string
+			if json.Unmarshal(value, &s) == nil {
+				v = s
+			}
+			if err := c.Set(section+"."+field, v); err != nil {
+				return fmt.Errorf("config %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyEnv applies every GOBP_ variable of environ, as returned by os.Environ.
+func (c *Config) ApplyEnv(environ []string) error {
+	keys := make(map[string]string)
+	for _, key := range c.Keys() {
+		keys[EnvVar(key)] = key
+	}
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, EnvPrefix) || name == EnvPrefix+"CONFIG" {
+			continue
+		}
+		key, ok := keys[name]
+		if !ok {
+			return fmt.Errorf("unknown environment variable %s", name)
+		}
+		if err := c.Set(key, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// EnvVar returns the environment variable that sets key.
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Keys returns every configuration key, sorted.
+func (c *Config) Keys() []string {
+	var keys []string
+	c.walk(func(key string, _ reflect.Value) { keys = append(keys, key) })
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the value of key in the same format Set accepts.
+func (c *Config) Get(key string) (string, bool) {
+	v, ok := c.field(key)
+	if !ok {
+		return "", false
+	}
+	if d, isDuration := v.Interface().(time.Duration); isDuration {
+		return d.String(), true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
+
+// Set parses value into key.
+func (c *Config) Set(key, value string) error {
+	v, ok := c.field(key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	var err error
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		var d time.Duration
+		if d, err = time.ParseDuration(value); err == nil {
+			v.SetInt(int64(d))
+		}
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Int:
+		var n int64
+		if n, err = strconv.ParseInt(value, 10, 0); err == nil {
+			v.SetInt(n)
+		}
+	case v.Kind() == reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(value, 64); err == nil {
+			v.SetFloat(f)
+		}
+	case v.Kind() == reflect.Uint32:
+		var n uint64
+		if n, err = strconv.ParseUint(value, 10, 32); err == nil {
+			v.SetUint(n)
+		}
+	default:
+		return fmt.Errorf("config key %q has unsupported type %s", key, v.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("config key %q: invalid value %q", key, value)
+	}
+	return nil
+}
+
+// Validate reports every invalid value at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	_, err := logging.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level must be debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format must be text or json, got %q", c.Log.Format)
+
+	u, err := url.Parse(c.Crawler.StartURL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+		"crawler.start_url must be an absolute http(s) URL, got %q", c.Crawler.StartURL)
+	check(c.Crawler.Depth > 0, "crawler.depth must be positive, got %d", c.Crawler.Depth)
+
+	check(c.Redis.Addr != "", "redis.addr must not be empty")
+	check(c.Redis.DB >= 0, "redis.db must not be negative, got %d", c.Redis.DB)
+	check(c.Redis.BudgetLimit > 0, "redis.budget_limit must be positive, got %d", c.Redis.BudgetLimit)
+
+	check(c.Breaker.Name != "", "breaker.name must not be empty")
+	check(c.Breaker.Interval >= 0, "breaker.interval must not be negative, got %v", c.Breaker.Interval)
+	check(c.Breaker.Timeout > 0, "breaker.timeout must be positive, got %v", c.Breaker.Timeout)
+	check(c.Breaker.FailureThreshold > 0, "breaker.failure_threshold must be positive, got %d", c.Breaker.FailureThreshold)
+	check(c.Breaker.Attempts > 0, "breaker.attempts must be positive, got %d", c.Breaker.Attempts)
+
+	check(c.Payment.Balance >= 0, "payment.balance must not be negative, got %d", c.Payment.Balance)
+	check(c.Payment.Amount > 0, "payment.amount must be positive, got %d", c.Payment.Amount)
+
+	check(c.Network.Latency >= 0, "network.latency must not be negative, got %v", c.Network.Latency)
+	check(c.Network.Jitter >= 0, "network.jitter must not be negative, got %v", c.Network.Jitter)
+	check(c.Network.Drop >= 0 && c.Network.Drop <= 1, "network.drop must be between 0 and 1, got %v", c.Network.Drop)
+	check(c.Network.Duplicate >= 0 && c.Network.Duplicate <= 1, "network.duplicate must be between 0 and 1, got %v", c.Network.Duplicate)
+	check(c.Network.Reorder >= 0 && c.Network.Reorder <= 1, "network.reorder must be between 0 and 1, got %v", c.Network.Reorder)
+
+	check(c.Detector.Threshold > 0, "detector.threshold must be positive, got %v", c.Detector.Threshold)
+	check(c.Detector.Heartbeat > 0, "detector.heartbeat must be positive, got %v", c.Detector.Heartbeat)
+	check(c.Detector.MinStdDev > 0, "detector.min_stddev must be positive, got %v", c.Detector.MinStdDev)
+	check(c.Detector.AcceptablePause >= 0, "detector.acceptable_pause must not be negative, got %v", c.Detector.AcceptablePause)
+	check(c.Detector.Window > 1, "detector.window must be at least 2, got %d", c.Detector.Window)
+
+	return errors.Join(errs...)
+}
+
+// Apply returns a copy of e whose parameter defaults come from the configuration,
+// for every parameter bound to a config key.
+func (c *Config) Apply(e registry.Example) registry.Example {
+	params := make([]registry.Param, len(e.Params))
+	for i, p := range e.Params {
+		if p.Key == "" {
+			params[i] = p
+			continue
+		}
+		if v, ok := c.Get(p.Key); ok {
+			p.Default = v
+		}
+		params[i] = p
+	}
+	e.Params = params
+	return e
+}
+
+// ValidateArgs checks the arguments of a run of e against the same rules as Validate,
+// so that demo flags and request parameters bound to config keys cannot bypass them.
+func (c *Config) ValidateArgs(e registry.Example, args registry.Args) error {
+	merged := *c
+	for _, p := range e.Params {
+		if p.Key == "" {
+			continue
+		}
+		if err := merged.Set(p.Key, args[p.Name]); err != nil {
+			return fmt.Errorf("%s: parameter %q: %w", e.Name, p.Name, err)
+		}
+	}
+	if err := merged.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", e.Name, err)
+	}
+	return nil
+}
+
+func (c *Config) field(key string) (reflect.Value, bool) {
+	var found reflect.Value
+	c.walk(func(k string, v reflect.Value) {
+		if k == key {
+			found = v
+		}
+	})
+	return found, found.IsValid()
+}
+
+// walk calls fn with the key and settable value of every field of every section
+func (c *Config) walk(fn func(key string, v reflect.Value)) {
+	root := reflect.ValueOf(c).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Field(i)
+		prefix := root.Type().Field(i).Tag.Get("json")
+		for j := 0; j < section.NumField(); j++ {
+			fn(prefix+"."+section.Type().Field(j).Tag.Get("json"), section.Field(j))
+		}
+	}
+}
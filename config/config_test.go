@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"GoBestPratices/registry"
+)
+
+func TestLoadLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{"crawler": {"depth": 2, "start_url": "https://go.dev"}, "breaker": {"timeout": "5s"}, "payment": {"amount": 100}}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The environment overrides the file, the command line overrides both
+	environ := []string{"GOBP_CRAWLER_DEPTH=4", "GOBP_PAYMENT_AMOUNT=200", "HOME=/root"}
+	overrides := []string{"payment.amount=300", "network.drop=0.25"}
+
+	cfg, err := Load(path, environ, overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"crawler.start_url from file", cfg.Crawler.StartURL, "https://go.dev"},
+		{"crawler.depth from env", cfg.Crawler.Depth, 4},
+		{"breaker.timeout from file", cfg.Breaker.Timeout, 5 * time.Second},
+		{"payment.amount from flag", cfg.Payment.Amount, 300},
+		{"redis.addr default", cfg.Redis.Addr, "localhost:6379"},
+		{"network.drop from flag", cfg.Network.Drop, 0.25},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v; want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		environ   []string
+		overrides []string
+	}{
+		{"unknown key", nil, []string{"crawler.speed=1"}},
+		{"malformed override", nil, []string{"crawler.depth"}},
+		{"wrong type", []string{"GOBP_BREAKER_TIMEOUT=soon"}, nil},
+		{"unknown variable", []string{"GOBP_REDIS_PASSWORD=x"}, nil},
+		{"fails validation", nil, []string{"crawler.start_url=ftp://example.com"}},
+		{"probability out of range", []string{"GOBP_NETWORK_REORDER=1.5"}, nil},
+		{"detector window too small", nil, []string{"detector.window=1"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Load("", tt.environ, tt.overrides); err == nil {
+			t.Errorf("%s: Load succeeded; want error", tt.name)
+		}
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	e := registry.Example{
+		Name: "test",
+		Params: []registry.Param{
+			{Name: "attempts", Type: registry.Int, Default: "10", Key: "breaker.attempts"},
+			{Name: "threshold", Type: registry.Int, Default: "2", Key: "breaker.failure_threshold"},
+			{Name: "label", Type: registry.String, Default: ""},
+		},
+		Run: func(context.Context, registry.Args) error { return nil },
+	}
+	cfg := Default()
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		valid  bool
+	}{
+		{"defaults", nil, true},
+		{"valid flags", map[string]string{"attempts": "3", "label": "x"}, true},
+		{"fails validation", map[string]string{"attempts": "0"}, false},
+		{"out of the key's range", map[string]string{"threshold": "-1"}, false},
+	}
+
+	for _, tt := range tests {
+		args, err := cfg.Apply(e).Parse(tt.values)
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tt.name, err)
+		}
+		if err := cfg.ValidateArgs(e, args); (err == nil) != tt.valid {
+			t.Errorf("%s: ValidateArgs = %v; want valid=%v", tt.name, err, tt.valid)
+		}
+	}
+	if cfg.Breaker.Attempts != 10 {
+		t.Errorf("ValidateArgs changed the configuration: breaker.attempts = %d", cfg.Breaker.Attempts)
+	}
+}
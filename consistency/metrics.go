@@ -0,0 +1,10 @@
+package consistency
+
+import "GoBestPratices/metrics"
+
+// Transaction and campaign metrics, exposed on /metrics
+var (
+	transactions     = metrics.NewCounter("payment_transactions_total", "Two-phase commit transactions by outcome (committed or aborted).", "outcome")
+	cashbackAccepted = metrics.NewCounter("cashback_accepted_total", "Cashback requests applied to the campaign budget.")
+	cashbackRejected = metrics.NewCounter("cashback_rejected_total", "Cashback requests refused, by reason.", "reason")
+)
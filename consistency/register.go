@@ -0,0 +1,43 @@
+package consistency
+
+import (
+	"context"
+
+	"GoBestPratices/config"
+	"GoBestPratices/registry"
+)
+
+func init() {
+	registry.Register(registry.Example{
+		Name:        "two-phase-commit",
+		Category:    "consistency",
+		Description: "Two-phase commit between account and receipt services",
+		Params: []registry.Param{
+			{Name: "balance", Type: registry.Int, Default: "1000", Usage: "initial account balance", Key: "payment.balance"},
+			{Name: "amount", Type: registry.Int, Default: "500", Usage: "payment amount", Key: "payment.amount"},
+		},
+		Run: func(ctx context.Context, args registry.Args) error {
+			return TwoPhaseCommit(ctx, config.Payment{
+				Balance: args.Int("balance"),
+				Amount:  args.Int("amount"),
+			})
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "redis-cashback",
+		Category:    "consistency",
+		Description: "Cashback campaign budget guarded by a Redis lock",
+		Params: []registry.Param{
+			{Name: "addr", Type: registry.String, Default: "localhost:6379", Usage: "Redis server address", Key: "redis.addr"},
+			{Name: "db", Type: registry.Int, Default: "0", Usage: "Redis database number", Key: "redis.db"},
+			{Name: "budget-limit", Type: registry.Int, Default: "10000", Usage: "campaign budget in euros", Key: "redis.budget_limit"},
+		},
+		Run: func(ctx context.Context, args registry.Args) error {
+			return ProcessWithRedis(ctx, config.Redis{
+				Addr:        args.String("addr"),
+				DB:          args.Int("db"),
+				BudgetLimit: args.Int("budget-limit"),
+			})
+		},
+	})
+}
@@ -1,8 +1,16 @@
 package consistency
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"GoBestPratices/clock"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+	"GoBestPratices/logging"
+	"GoBestPratices/tracing"
 )
 
 //Two-Phase Commit (2PC) Example for Distributed Transactions
@@ -11,9 +19,16 @@ import (
 //Phase 1: The coordinator sends a prepare request to the participants (services).
 //Phase 2: If all participants vote commit, the coordinator sends a commit request. If any participant votes abort, the coordinator sends an abort request.
 
-func TwoPhaseCommit() {
+// ErrTransactionAborted is returned when a participant votes to abort the transaction.
+var ErrTransactionAborted = errors.New("transaction aborted")
+
+func TwoPhaseCommit(ctx context.Context, cfg config.Payment) error {
+	if cfg.Amount <= 0 {
+		return fmt.Errorf("amount must be positive, got %d", cfg.Amount)
+	}
+
 	// Simulating distributed services
-	accountService := &AccountService{balance: 1000}
+	accountService := &AccountService{balance: cfg.Balance}
 	receiptService := &ReceiptService{receiptID: 1}
 
 	// Create a Payment Service that communicates with both services
@@ -23,16 +38,20 @@ func TwoPhaseCommit() {
 	}
 
 	// Attempt a payment transaction
-	fmt.Println("Starting transaction...")
-
-	// Example payment amount
-	amount := 500
+	events.Emit(ctx, events.Note{Text: "Starting transaction..."})
 
 	// Process the transaction
-	paymentService.processTransaction(amount)
+	committed := paymentService.processTransaction(ctx, receiptService.receiptID, cfg.Amount)
 
 	// Final state of the account after transaction
-	fmt.Println("Final account balance:", accountService.balance)
+	events.Emit(ctx, events.Result{Name: "Final account balance", Value: accountService.balance})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !committed {
+		return ErrTransactionAborted
+	}
+	return nil
 }
 
 // Simulated distributed system services
@@ -50,30 +69,56 @@ type PaymentService struct {
 }
 
 // Prepare phase: Checks if AccountService and ReceiptService are ready to commit the transaction
-func (s *AccountService) preparePayment(amount int) bool {
+func (s *AccountService) preparePayment(ctx context.Context, transactionID, amount int) bool {
+	ctx, span := tracing.Start(ctx, "2pc.prepare", "participant", "account", "balance", s.balance)
+	defer span.End()
+
 	// Simulate account balance check before transaction
 	if s.balance >= amount {
-		fmt.Println("Account service: Balance sufficient for transaction.")
+		span.AddEvent("vote", "commit", true)
+		events.Emit(ctx, events.VoteCast{TxID: transactionID, Participant: "Account service", Commit: true, Reason: "balance sufficient"})
 		return true
 	}
-	fmt.Println("Account service: Insufficient funds.")
+	span.AddEvent("vote", "commit", false, "reason", "insufficient funds")
+	events.Emit(ctx, events.VoteCast{TxID: transactionID, Participant: "Account service", Commit: false, Reason: "insufficient funds"})
 	return false
 }
 
-func (s *ReceiptService) prepareReceipt(transactionID int) bool {
+func (s *ReceiptService) prepareReceipt(ctx context.Context, transactionID int) bool {
+	ctx, span := tracing.Start(ctx, "2pc.prepare", "participant", "receipt")
+	defer span.End()
+
 	// Simulate preparing a receipt
-	fmt.Println("Receipt service: Preparing receipt for transaction", transactionID)
+	span.AddEvent("vote", "commit", true)
+	events.Emit(ctx, events.VoteCast{TxID: transactionID, Participant: "Receipt service", Commit: true, Reason: "receipt prepared"})
 	return true
 }
 
+// Commit phase: each participant applies its part of the transaction
+func (s *AccountService) commitPayment(ctx context.Context, amount int) {
+	_, span := tracing.Start(ctx, "2pc.commit", "participant", "account")
+	defer span.End()
+
+	s.balance -= amount
+	span.SetAttributes("balance", s.balance)
+}
+
+func (s *ReceiptService) commitReceipt(ctx context.Context, transactionID int) {
+	_, span := tracing.Start(ctx, "2pc.commit", "participant", "receipt", "receipt_id", s.receiptID)
+	defer span.End()
+
+	// Simulate issuing the receipt prepared in phase 1
+	logging.FromContext(ctx).Debug("receipt issued", "receipt_id", s.receiptID)
+}
+
 // 2PC Phase 1: Prepare Phase
-func (ps *PaymentService) prepareTransaction(amount int) bool {
+func (ps *PaymentService) prepareTransaction(ctx context.Context, transactionID, amount int) bool {
 	// Phase 1: Ask each service if they are ready to commit
-	if !ps.accountService.preparePayment(amount) {
+	if !ps.accountService.preparePayment(ctx, transactionID, amount) {
 		return false
 	}
 
-	if !ps.receiptService.prepareReceipt(amount) {
+	if !ps.receiptService.prepareReceipt(ctx, transactionID) {
 		return false
 	}
 
@@ -81,30 +126,53 @@ func (ps *PaymentService) prepareTransaction(amount int) bool {
 }
 
 // 2PC Phase 2: Commit or Abort Phase
-func (ps *PaymentService) commitTransaction(amount int) bool {
+func (ps *PaymentService) commitTransaction(ctx context.Context, transactionID, amount int) bool {
 	// Phase 2: Commit the transaction if all services are ready
-	ps.accountService.balance -= amount
-	fmt.Println("Payment service: Transaction committed. Account balance is now", ps.accountService.balance)
+	ps.accountService.commitPayment(ctx, amount)
+	ps.receiptService.commitReceipt(ctx, transactionID)
+	events.Emit(ctx, events.TransactionCommitted{TxID: transactionID, Amount: amount, Balance: ps.accountService.balance})
 	return true
 }
 
 // 2PC: Handle transaction with retries for consistency
-func (ps *PaymentService) processTransaction(amount int) {
+func (ps *PaymentService) processTransaction(ctx context.Context, transactionID, amount int) bool {
+	ctx = logging.With(ctx, "tx_id", transactionID, "amount", amount)
+	logger := logging.FromContext(ctx)
+	ctx, span := tracing.Start(ctx, "2pc.transaction", "tx_id", transactionID, "amount", amount)
+	defer span.End()
+
 	// Phase 1: Prepare transaction
-	fmt.Println("Payment service: Starting Phase 1 - Prepare transaction.")
-	if !ps.prepareTransaction(amount) {
-		fmt.Println("Payment service: Transaction failed in Phase 1. Aborting.")
-		return
+	events.Emit(ctx, events.PhaseStarted{TxID: transactionID, Phase: 1, Name: "Prepare"})
+	if !ps.prepareTransaction(ctx, transactionID, amount) {
+		transactions.Inc("aborted")
+		span.SetAttributes("outcome", "aborted", "phase", 1)
+		logger.Warn("transaction aborted in prepare phase")
+		events.Emit(ctx, events.TransactionAborted{TxID: transactionID, Phase: 1, Reason: "participant voted abort"})
+		return false
 	}
 
 	// Simulate a potential failure in the network or service during commit phase
-	time.Sleep(1 * time.Second) // Simulate delay
+	// Nothing is applied yet, so a cancellation here safely aborts the transaction
+	if err := clock.Sleep(ctx, 1*time.Second); err != nil { // Simulate delay
+		transactions.Inc("aborted")
+		span.SetAttributes("outcome", "aborted", "phase", 1)
+		span.RecordError(err)
+		logger.Warn("transaction aborted before commit", "err", err)
+		events.Emit(ctx, events.TransactionAborted{TxID: transactionID, Phase: 1, Reason: "cancelled: " + err.Error()})
+		return false
+	}
 
 	// Phase 2: Commit transaction
-	fmt.Println("Payment service: Starting Phase 2 - Commit transaction.")
-	if ps.commitTransaction(amount) {
-		fmt.Println("Payment service: Transaction completed successfully.")
-	} else {
-		fmt.Println("Payment service: Transaction failed in Phase 2. Rolling back.")
+	events.Emit(ctx, events.PhaseStarted{TxID: transactionID, Phase: 2, Name: "Commit"})
+	if ps.commitTransaction(ctx, transactionID, amount) {
+		transactions.Inc("committed")
+		span.SetAttributes("outcome", "committed")
+		logger.Info("transaction committed")
+		return true
 	}
+	transactions.Inc("aborted")
+	span.SetAttributes("outcome", "aborted", "phase", 2)
+	logger.Error("transaction failed in commit phase")
+	events.Emit(ctx, events.TransactionAborted{TxID: transactionID, Phase: 2, Reason: "commit failed, rolling back"})
+	return false
 }
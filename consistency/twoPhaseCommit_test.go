@@ -0,0 +1,60 @@
+package consistency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"GoBestPratices/clock"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+	"GoBestPratices/tracing"
+)
+
+func TestTwoPhaseCommit(t *testing.T) {
+	tests := []struct {
+		balance, amount int
+		wantErr         error
+		wantVotes       int
+		wantSpans       int  // the transaction plus one span per participant and phase reached
+		sleeps          bool // only a prepared transaction waits before committing
+	}{
+		{balance: 1000, amount: 500, wantVotes: 2, wantSpans: 5, sleeps: true},
+		{balance: 100, amount: 500, wantErr: ErrTransactionAborted, wantVotes: 1, wantSpans: 2},
+	}
+
+	for _, tt := range tests {
+		fake := clock.NewFake(time.Unix(0, 0))
+		rec := &events.Recorder{}
+		spans := &tracing.InMemoryExporter{}
+		ctx := events.WithSink(clock.WithClock(context.Background(), fake), rec)
+		ctx = tracing.WithTracer(ctx, tracing.NewTracer(spans))
+
+		done := make(chan error, 1)
+		go func() { done <- TwoPhaseCommit(ctx, config.Payment{Balance: tt.balance, Amount: tt.amount}) }()
+
+		// The coordinator waits between the two phases; release it without sleeping
+		if tt.sleeps {
+			fake.BlockUntil(1)
+			fake.Advance(time.Second)
+		}
+		err := <-done
+
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("TwoPhaseCommit(%d, %d) = %v; want %v", tt.balance, tt.amount, err, tt.wantErr)
+		}
+		votes := 0
+		for _, e := range rec.Events() {
+			if _, ok := e.(events.VoteCast); ok {
+				votes++
+			}
+		}
+		if votes != tt.wantVotes {
+			t.Errorf("TwoPhaseCommit(%d, %d) cast %d votes; want %d", tt.balance, tt.amount, votes, tt.wantVotes)
+		}
+		if got := len(spans.Spans()); got != tt.wantSpans {
+			t.Errorf("TwoPhaseCommit(%d, %d) recorded %d spans; want %d", tt.balance, tt.amount, got, tt.wantSpans)
+		}
+	}
+}
@@ -3,28 +3,32 @@ package consistency
 import (
 	"context"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
-	"github.com/go-redis/redis/v8"
-)
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+	"GoBestPratices/logging"
+	"GoBestPratices/tracing"
 
-var (
-	ctx = context.Background()
-	rdb *redis.Client
+	"github.com/go-redis/redis/v8"
 )
 
-const campaignBudgetLimit = 10000
-
-var currentSpend int
-
-func ProcessWithRedis() {
+func ProcessWithRedis(ctx context.Context, cfg config.Redis) error {
 	// Initialize Redis client
-	rdb = redis.NewClient(&redis.Options{
-		Addr: "localhost:6379", // Redis server address
-		DB:   0,                // Default DB
+	rdb := redis.NewClient(&redis.Options{
+		Addr: cfg.Addr, // Redis server address
+		DB:   cfg.DB,   // Redis database number
 	})
+	defer rdb.Close()
+
+	ctx = logging.With(ctx, "redis_addr", cfg.Addr)
+	logger := logging.FromContext(ctx)
+
+	// Fail fast when the Redis server is not reachable
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("connecting to redis at %s: %w", cfg.Addr, err)
+	}
 
 	// Initialize campaign state
 	rdb.Set(ctx, "campaign_total_spend", 0, 0)   // Initial total spend is 0
@@ -46,69 +50,96 @@ func ProcessWithRedis() {
 	}
 
 	for _, user := range users {
-		go processCashback(user.userID, user.cashbackAmount, &wg)
+		go processCashback(ctx, rdb, user.userID, user.cashbackAmount, cfg.BudgetLimit, &wg)
 	}
 
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// Final state of the campaign
 	finalTotalSpend, err := rdb.Get(ctx, "campaign_total_spend").Int()
 	if err != nil {
-		log.Printf("Error fetching final total spend: %v", err)
+		logger.Error("fetching final total spend", "err", err)
 	}
-	fmt.Printf("Final total spend: €%d\n", finalTotalSpend)
+	events.Emit(ctx, events.Result{Name: "Final total spend", Value: finalTotalSpend})
 
 	// Check if the campaign is finished
 	status, err := rdb.Get(ctx, "campaign_status").Result()
 	if err != nil {
-		log.Printf("Error fetching campaign status: %v", err)
+		logger.Error("fetching campaign status", "err", err)
 	}
-	fmt.Println("Campaign status:", status)
+	events.Emit(ctx, events.Result{Name: "Campaign status", Value: status})
+	return nil
 }
 
 // Simulated service processing cashback requests
-func processCashback(userID string, cashbackAmount int, wg *sync.WaitGroup) {
+func processCashback(ctx context.Context, rdb *redis.Client, userID string, cashbackAmount, budgetLimit int, wg *sync.WaitGroup) {
 	defer wg.Done()
+	ctx = logging.With(ctx, "user_id", userID, "cashback", cashbackAmount)
+	logger := logging.FromContext(ctx)
+	ctx, span := tracing.Start(ctx, "cashback.process", "user_id", userID, "amount", cashbackAmount)
+	defer span.End()
 
 	// Acquire a lock before updating campaign state (to ensure no race condition)
 	lockKey := "campaign_lock"
+	_, lockSpan := tracing.Start(ctx, "cashback.lock_acquire", "key", lockKey)
 	ok, err := rdb.SetNX(ctx, lockKey, 1, 10*time.Second).Result()
+	lockSpan.SetAttributes("acquired", ok)
+	lockSpan.RecordError(err)
+	lockSpan.End()
 	if err != nil {
-		log.Printf("Error acquiring lock: %v", err)
+		logger.Error("acquiring campaign lock", "err", err)
 		return
 	}
 
 	if !ok {
-		log.Println("Lock not acquired, skipping...")
+		span.SetAttributes("outcome", "lock_not_acquired")
+		cashbackRejected.Inc("lock_not_acquired")
+		events.Emit(ctx, events.CashbackRejected{User: userID, Amount: cashbackAmount, Reason: "lock not acquired"})
 		return
 	}
-	defer rdb.Del(ctx, lockKey) // Ensure the lock is released after processing
+	// Ensure the lock is released after processing, even when ctx was cancelled meanwhile
+	defer rdb.Del(context.WithoutCancel(ctx), lockKey)
 
 	// Check if the remaining budget allows the cashback
+	_, budgetSpan := tracing.Start(ctx, "cashback.budget_check", "budget_limit", budgetLimit)
 	currentTotalSpend, err := rdb.Get(ctx, "campaign_total_spend").Int()
+	budgetSpan.SetAttributes("total_spend", currentTotalSpend)
 	if err != nil && err != redis.Nil {
-		log.Printf("Error fetching current total spend: %v", err)
+		budgetSpan.RecordError(err)
+		budgetSpan.End()
+		logger.Error("fetching current total spend", "err", err)
 		return
 	}
+	budgetSpan.End()
 
 	// If the remaining budget is insufficient, cancel the cashback
-	if currentTotalSpend+cashbackAmount > campaignBudgetLimit {
-		log.Printf("Insufficient funds. Cannot process cashback for user %s.\n", userID)
+	if currentTotalSpend+cashbackAmount > budgetLimit {
+		span.SetAttributes("outcome", "insufficient_budget")
+		cashbackRejected.Inc("insufficient_budget")
+		events.Emit(ctx, events.CashbackRejected{User: userID, Amount: cashbackAmount, Reason: "insufficient campaign budget"})
 		return
 	}
 
 	// Apply the cashback by updating the total spend
+	_, incrSpan := tracing.Start(ctx, "cashback.increment")
 	_, err = rdb.IncrBy(ctx, "campaign_total_spend", int64(cashbackAmount)).Result()
+	incrSpan.RecordError(err)
+	incrSpan.End()
 	if err != nil {
-		log.Printf("Error updating total spend: %v", err)
+		logger.Error("updating total spend", "err", err)
 		return
 	}
 
-	fmt.Printf("Processed cashback of €%d for user %s. Current total spend: €%d\n", cashbackAmount, userID, currentTotalSpend+cashbackAmount)
+	span.SetAttributes("outcome", "accepted")
+	cashbackAccepted.Inc()
+	events.Emit(ctx, events.CashbackAccepted{User: userID, Amount: cashbackAmount, TotalSpend: currentTotalSpend + cashbackAmount})
 
 	// If the total spend has reached or exceeded the limit, mark the campaign as finished
-	if currentTotalSpend+cashbackAmount >= campaignBudgetLimit {
-		fmt.Println("Campaign budget limit reached. Ending campaign.")
+	if currentTotalSpend+cashbackAmount >= budgetLimit {
+		events.Emit(ctx, events.CampaignFinished{TotalSpend: currentTotalSpend + cashbackAmount})
 		rdb.Set(ctx, "campaign_status", "finished", 0) // Mark campaign as finished
 	}
 }
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"GoBestPratices/clock"
+)
+
+//Demos report what happened as typed events instead of printing strings.
+//A Sink decides what to do with them: render them as text or JSON lines, record them for a test,
+//or forward them to a client. The sink travels in the context, so every run can have its own.
+
+// Event is implemented by every typed event. Kind is a stable snake_case name used by renderers.
+type Event interface {
+	Kind() string
+}
+
+// Record is an event stamped with the time it was emitted.
+type Record struct {
+	Time  time.Time
+	Event Event
+}
+
+// Sink receives the events emitted by a demo. Implementations must be safe for concurrent use.
+type Sink interface {
+	Emit(r Record)
+}
+
+type sinkKey struct{}
+
+// WithSink returns a context whose events are sent to s.
+func WithSink(ctx context.Context, s Sink) context.Context {
+	return context.WithValue(ctx, sinkKey{}, s)
+}
+
+// defaultSink keeps the demos readable when they run without a configured sink.
+var defaultSink Sink = NewTextSink(os.Stdout)
+
+// FromContext returns the sink carried by ctx, or a text sink on stdout.
+func FromContext(ctx context.Context) Sink {
+	if s, ok := ctx.Value(sinkKey{}).(Sink); ok {
+		return s
+	}
+	return defaultSink
+}
+
+// Emit sends e to the sink carried by ctx, stamped with the clock carried by ctx.
+func Emit(ctx context.Context, e Event) {
+	FromContext(ctx).Emit(Record{Time: clock.FromContext(ctx).Now(), Event: e})
+}
+
+// Discard drops every event.
+var Discard Sink = discard{}
+
+type discard struct{}
+
+func (discard) Emit(Record) {}
+
+// Multi sends every event to all the given sinks.
+func Multi(sinks ...Sink) Sink {
+	return multi(sinks)
+}
+
+type multi []Sink
+
+func (m multi) Emit(r Record) {
+	for _, s := range m {
+		s.Emit(r)
+	}
+}
+
+// Recorder keeps every event in memory, mostly for tests.
+type Recorder struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (r *Recorder) Emit(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, rec)
+}
+
+// Events returns the recorded events in emission order.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]Event, len(r.records))
+	for i, rec := range r.records {
+		events[i] = rec.Event
+	}
+	return events
+}
+
+// Records returns the recorded events with their timestamps.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Record(nil), r.records...)
+}
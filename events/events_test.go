@@ -0,0 +1,61 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestEmitUsesContextSink(t *testing.T) {
+	rec := &Recorder{}
+	ctx := WithSink(context.Background(), rec)
+
+	Emit(ctx, WriteAccepted{System: "cp", Value: "v1"})
+	Emit(ctx, WriteRejected{System: "cp", Value: "v2", Reason: "partition"})
+
+	got := rec.Events()
+	if len(got) != 2 {
+		t.Fatalf("recorded %d events; want 2", len(got))
+	}
+	if w, ok := got[0].(WriteAccepted); !ok || w.Value != "v1" {
+		t.Errorf("first event = %#v; want WriteAccepted v1", got[0])
+	}
+	if _, ok := got[1].(WriteRejected); !ok {
+		t.Errorf("second event = %#v; want WriteRejected", got[1])
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	tests := []struct {
+		event Event
+		text  string
+		kind  string
+	}{
+		{JobProcessed{Worker: 1, Job: 2, Result: 4}, "Worker 1 processing job 2\n", "job_processed"},
+		{VoteCast{TxID: 1, Participant: "Account service", Commit: false, Reason: "insufficient funds"},
+			"Account service: votes abort for transaction 1 (insufficient funds).\n", "vote_cast"},
+		{PageFetched{URL: "https://example.com", Depth: 1, Links: 3}, "Crawled: https://example.com (3 links)\n", "page_fetched"},
+	}
+
+	for _, tt := range tests {
+		var text, lines bytes.Buffer
+		Emit(WithSink(context.Background(), Multi(NewTextSink(&text), NewJSONSink(&lines))), tt.event)
+
+		if text.String() != tt.text {
+			t.Errorf("text render of %T = %q; want %q", tt.event, text.String(), tt.text)
+		}
+
+		var decoded struct {
+			Kind  string          `json:"kind"`
+			Event json.RawMessage `json:"event"`
+		}
+		if err := json.Unmarshal(lines.Bytes(), &decoded); err != nil {
+			t.Errorf("JSON render of %T is not valid JSON: %v", tt.event, err)
+			continue
+		}
+		if decoded.Kind != tt.kind || len(decoded.Event) == 0 {
+			t.Errorf("JSON render of %T = %s; want kind %q with a payload", tt.event, lines.String(), tt.kind)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// TextSink renders each event on its own line using its String method.
+type TextSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewTextSink(w io.Writer) *TextSink {
+	return &TextSink{w: w}
+}
+
+func (s *TextSink) Emit(r Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintln(s.w, r.Event)
+}
+
+// JSONSink renders each event as one JSON object per line:
+// {"time":"...","kind":"write_accepted","event":{...}}
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONSink) Emit(r Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Encoding only fails for unsupported types, which typed events never contain
+	_ = s.enc.Encode(Marshal(r))
+}
+
+// JSONRecord is the wire format of a record, shared by the JSON renderers.
+type JSONRecord struct {
+	Time  time.Time `json:"time"`
+	Kind  string    `json:"kind"`
+	Event Event     `json:"event"`
+}
+
+func Marshal(r Record) JSONRecord {
+	return JSONRecord{Time: r.Time, Kind: r.Event.Kind(), Event: r.Event}
+}
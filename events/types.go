@@ -0,0 +1,372 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Note is free text for demos that only narrate what they do.
+type Note struct {
+	Text string `json:"text"`
+}
+
+func (Note) Kind() string     { return "note" }
+func (e Note) String() string { return e.Text }
+
+// Result reports a named final value, such as a counter or a balance.
+type Result struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+func (Result) Kind() string     { return "result" }
+func (e Result) String() string { return fmt.Sprintf("%s: %v", e.Name, e.Value) }
+
+// CAP theorem
+
+// WriteAccepted is emitted when a system stores a value.
+type WriteAccepted struct {
+	System string `json:"system"`
+	Node   string `json:"node,omitempty"`
+	Value  string `json:"value"`
+}
+
+func (WriteAccepted) Kind() string { return "write_accepted" }
+func (e WriteAccepted) String() string {
+	return fmt.Sprintf("%s: Data written: %s", capPrefix(e.System, e.Node), e.Value)
+}
+
+// WriteRejected is emitted when a system refuses a write to stay consistent.
+type WriteRejected struct {
+	System string `json:"system"`
+	Node   string `json:"node,omitempty"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+func (WriteRejected) Kind() string { return "write_rejected" }
+func (e WriteRejected) String() string {
+	return fmt.Sprintf("%s: %s. Cannot write data.", capPrefix(e.System, e.Node), e.Reason)
+}
+
+// ReadServed is emitted when a system answers a read.
+type ReadServed struct {
+	System string `json:"system"`
+	Node   string `json:"node,omitempty"`
+	Value  string `json:"value"`
+}
+
+func (ReadServed) Kind() string { return "read_served" }
+func (e ReadServed) String() string {
+	return fmt.Sprintf("%s: Read: %s", capPrefix(e.System, e.Node), e.Value)
+}
+
+// ReadRejected is emitted when a system refuses a read.
+type ReadRejected struct {
+	System string `json:"system"`
+	Node   string `json:"node,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (ReadRejected) Kind() string { return "read_rejected" }
+func (e ReadRejected) String() string {
+	return fmt.Sprintf("%s: %s. Cannot read data.", capPrefix(e.System, e.Node), e.Reason)
+}
+
+// PartitionChanged is emitted when a network partition starts or heals.
+type PartitionChanged struct {
+	System      string     `json:"system"`
+	Partitioned bool       `json:"partitioned"`
+	Groups      [][]string `json:"groups,omitempty"`
+}
+
+func (PartitionChanged) Kind() string { return "partition_changed" }
+func (e PartitionChanged) String() string {
+	if e.Partitioned {
+		if len(e.Groups) > 0 {
+			return fmt.Sprintf("%s: Network partition started %s", e.System, formatGroups(e.Groups))
+		}
+		return fmt.Sprintf("%s: Network partition started", e.System)
+	}
+	return fmt.Sprintf("%s: Network partition healed", e.System)
+}
+
+// ReplicaState is emitted to show the value every node holds for a key,
+// which makes divergent replicas visible.
+type ReplicaState struct {
+	System string            `json:"system"`
+	Key    string            `json:"key"`
+	Values map[string]string `json:"values"`
+}
+
+func (ReplicaState) Kind() string { return "replica_state" }
+func (e ReplicaState) String() string {
+	nodes := make([]string, 0, len(e.Values))
+	for node := range e.Values {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = fmt.Sprintf("%s=%q", node, e.Values[node])
+	}
+	return fmt.Sprintf("%s: Replicas of %s: %s", e.System, e.Key, strings.Join(parts, " "))
+}
+
+// SiblingsFound is emitted when a read returns concurrent versions of a key
+// that the client has to merge.
+type SiblingsFound struct {
+	System   string   `json:"system"`
+	Node     string   `json:"node,omitempty"`
+	Key      string   `json:"key"`
+	Siblings []string `json:"siblings"`
+}
+
+func (SiblingsFound) Kind() string { return "siblings_found" }
+func (e SiblingsFound) String() string {
+	return fmt.Sprintf("%s: %d conflicting versions of %s: %q", capPrefix(e.System, e.Node), len(e.Siblings), e.Key, e.Siblings)
+}
+
+// LeaderElected is emitted when a consensus group has a new leader.
+type LeaderElected struct {
+	System string `json:"system"`
+	Node   string `json:"node"`
+	Term   uint64 `json:"term"`
+}
+
+func (LeaderElected) Kind() string { return "leader_elected" }
+func (e LeaderElected) String() string {
+	return fmt.Sprintf("%s: %s is the leader of term %d", e.System, e.Node, e.Term)
+}
+
+func capPrefix(system, node string) string {
+	if node == "" {
+		return system
+	}
+	return fmt.Sprintf("%s[%s]", system, node)
+}
+
+func formatGroups(groups [][]string) string {
+	parts := make([]string, len(groups))
+	for i, g := range groups {
+		parts[i] = "{" + strings.Join(g, ",") + "}"
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Concurrency
+
+// TaskCompleted is emitted by a goroutine when it finishes its task.
+type TaskCompleted struct {
+	Task int `json:"task"`
+}
+
+func (TaskCompleted) Kind() string     { return "task_completed" }
+func (e TaskCompleted) String() string { return fmt.Sprintf("Goroutine %d", e.Task) }
+
+// JobProcessed is emitted by a worker of a pool for every job it handles.
+type JobProcessed struct {
+	Worker int `json:"worker"`
+	Job    int `json:"job"`
+	Result int `json:"result"`
+}
+
+func (JobProcessed) Kind() string { return "job_processed" }
+func (e JobProcessed) String() string {
+	return fmt.Sprintf("Worker %d processing job %d", e.Worker, e.Job)
+}
+
+// WorkFinished is emitted when a unit of work completes or is cancelled.
+type WorkFinished struct {
+	Cancelled bool   `json:"cancelled"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func (WorkFinished) Kind() string { return "work_finished" }
+func (e WorkFinished) String() string {
+	if e.Cancelled {
+		return "Work cancelled: " + e.Reason
+	}
+	return "Work done"
+}
+
+// Two-phase commit
+
+// PhaseStarted is emitted by the coordinator when a 2PC phase begins.
+type PhaseStarted struct {
+	TxID  int    `json:"tx_id"`
+	Phase int    `json:"phase"`
+	Name  string `json:"name"`
+}
+
+func (PhaseStarted) Kind() string { return "phase_started" }
+func (e PhaseStarted) String() string {
+	return fmt.Sprintf("Payment service: Starting Phase %d - %s transaction %d.", e.Phase, e.Name, e.TxID)
+}
+
+// VoteCast is emitted by a participant answering the prepare request.
+type VoteCast struct {
+	TxID        int    `json:"tx_id"`
+	Participant string `json:"participant"`
+	Commit      bool   `json:"commit"`
+	Reason      string `json:"reason"`
+}
+
+func (VoteCast) Kind() string { return "vote_cast" }
+func (e VoteCast) String() string {
+	vote := "commit"
+	if !e.Commit {
+		vote = "abort"
+	}
+	return fmt.Sprintf("%s: votes %s for transaction %d (%s).", e.Participant, vote, e.TxID, e.Reason)
+}
+
+// TransactionCommitted is emitted once every participant applied the transaction.
+type TransactionCommitted struct {
+	TxID    int `json:"tx_id"`
+	Amount  int `json:"amount"`
+	Balance int `json:"balance"`
+}
+
+func (TransactionCommitted) Kind() string { return "transaction_committed" }
+func (e TransactionCommitted) String() string {
+	return fmt.Sprintf("Payment service: Transaction %d committed. Account balance is now %d", e.TxID, e.Balance)
+}
+
+// TransactionAborted is emitted when the coordinator aborts a transaction.
+type TransactionAborted struct {
+	TxID   int    `json:"tx_id"`
+	Phase  int    `json:"phase"`
+	Reason string `json:"reason"`
+}
+
+func (TransactionAborted) Kind() string { return "transaction_aborted" }
+func (e TransactionAborted) String() string {
+	return fmt.Sprintf("Payment service: Transaction %d failed in Phase %d (%s). Aborting.", e.TxID, e.Phase, e.Reason)
+}
+
+// Cashback campaign
+
+// CashbackAccepted is emitted when a cashback fits in the campaign budget.
+type CashbackAccepted struct {
+	User       string `json:"user"`
+	Amount     int    `json:"amount"`
+	TotalSpend int    `json:"total_spend"`
+}
+
+func (CashbackAccepted) Kind() string { return "cashback_accepted" }
+func (e CashbackAccepted) String() string {
+	return fmt.Sprintf("Processed cashback of €%d for user %s. Current total spend: €%d", e.Amount, e.User, e.TotalSpend)
+}
+
+// CashbackRejected is emitted when a cashback cannot be applied.
+type CashbackRejected struct {
+	User   string `json:"user"`
+	Amount int    `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+func (CashbackRejected) Kind() string { return "cashback_rejected" }
+func (e CashbackRejected) String() string {
+	return fmt.Sprintf("Cashback of €%d for user %s rejected: %s", e.Amount, e.User, e.Reason)
+}
+
+// CampaignFinished is emitted when the campaign budget is exhausted.
+type CampaignFinished struct {
+	TotalSpend int `json:"total_spend"`
+}
+
+func (CampaignFinished) Kind() string { return "campaign_finished" }
+func (e CampaignFinished) String() string {
+	return fmt.Sprintf("Campaign budget limit reached at €%d. Ending campaign.", e.TotalSpend)
+}
+
+// Circuit breaker
+
+// CallSucceeded is emitted when a protected call succeeds.
+type CallSucceeded struct {
+	Attempt int `json:"attempt"`
+}
+
+func (CallSucceeded) Kind() string { return "call_succeeded" }
+func (e CallSucceeded) String() string {
+	return fmt.Sprintf("Attempt #%d: API call succeeded", e.Attempt)
+}
+
+// CallFailed is emitted when a protected call reaches the service and fails.
+type CallFailed struct {
+	Attempt int    `json:"attempt"`
+	Err     string `json:"error"`
+}
+
+func (CallFailed) Kind() string { return "call_failed" }
+func (e CallFailed) String() string {
+	return fmt.Sprintf("Attempt #%d: API call failed: %s", e.Attempt, e.Err)
+}
+
+// CallRejected is emitted when the breaker refuses a call without reaching the service.
+type CallRejected struct {
+	Attempt int    `json:"attempt"`
+	Reason  string `json:"reason"`
+}
+
+func (CallRejected) Kind() string { return "call_rejected" }
+func (e CallRejected) String() string {
+	return fmt.Sprintf("Attempt #%d: rejected by circuit breaker: %s", e.Attempt, e.Reason)
+}
+
+// BreakerStateChanged is emitted on every circuit breaker transition.
+type BreakerStateChanged struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (BreakerStateChanged) Kind() string { return "breaker_state_changed" }
+func (e BreakerStateChanged) String() string {
+	return fmt.Sprintf("Circuit breaker %s: %s -> %s", e.Name, e.From, e.To)
+}
+
+// Web crawler
+
+// PageFetched is emitted when the crawler downloaded and parsed a page.
+type PageFetched struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+	Links int    `json:"links"`
+}
+
+func (PageFetched) Kind() string { return "page_fetched" }
+func (e PageFetched) String() string {
+	return fmt.Sprintf("Crawled: %s (%d links)", e.URL, e.Links)
+}
+
+// PageFailed is emitted when the crawler could not fetch a page.
+type PageFailed struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+	Err   string `json:"error"`
+}
+
+func (PageFailed) Kind() string { return "page_failed" }
+func (e PageFailed) String() string {
+	return fmt.Sprintf("Error fetching %s: %s", e.URL, e.Err)
+}
+
+// Command line
+
+// RunSummary is emitted once the command line finished running demos, even when interrupted.
+type RunSummary struct {
+	Succeeded []string      `json:"succeeded"`
+	Failed    []string      `json:"failed"`
+	Cancelled []string      `json:"cancelled"`
+	Elapsed   time.Duration `json:"elapsed_ns"`
+}
+
+func (RunSummary) Kind() string { return "run_summary" }
+func (e RunSummary) String() string {
+	return fmt.Sprintf("Summary: %d succeeded, %d failed, %d cancelled in %v",
+		len(e.Succeeded), len(e.Failed), len(e.Cancelled), e.Elapsed.Round(time.Millisecond))
+}
@@ -4,7 +4,6 @@ go 1.23.2
 
 require (
 	github.com/go-redis/redis/v8 v8.11.5
-	github.com/sony/gobreaker v1.0.0
 	golang.org/x/net v0.0.0-20210428140749-89ef3d95e781
 )
 
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+//Diagnostics go through one slog logger carried by the context.
+//Each layer adds the attributes it knows about (demo, worker, transaction, URL...) with With,
+//so a log line deep in a goroutine still says where it comes from.
+//Library code only logs and returns errors; it never exits the process.
+
+// New returns a logger writing to w in the given format ("text" or "json") at the given level.
+func New(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, want text or json", format)
+	}
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, want debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a context carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// With returns a context whose logger adds the given attributes to every record.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
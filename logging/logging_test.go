@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestWithCarriesAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithLogger(context.Background(), logger)
+	ctx = With(ctx, "worker", 2)
+	FromContext(ctx).Debug("hidden below the level")
+	FromContext(ctx).Info("processing job", "job", 7)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected exactly one JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["worker"] != float64(2) || record["job"] != float64(7) {
+		t.Errorf("record = %v; want worker 2 and job 7", record)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"loud", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -1,6 +1,18 @@
 package main
 
-import "GoBestPratices/pratices"
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	// Every example package registers its demos with the registry
+	_ "GoBestPratices/capTheorem"
+	_ "GoBestPratices/concurrency"
+	_ "GoBestPratices/consistency"
+	_ "GoBestPratices/pratices"
+	_ "GoBestPratices/resilience"
+)
 
 // Structuring Large Go Projects
 // Follow Standard Go Project Layout (pkg/, cmd/, internal/).
@@ -13,18 +25,15 @@ import "GoBestPratices/pratices"
 //├── docs/       # Documentation
 
 func main() {
-	/*pratices.GoRoutine()
-	pratices.RunChannel()
-	pratices.ChannelGoRoutine()
-	pratices.Context()
-	pratices.ErrorHandling()
-	pratices.Optimizing()
-	pratices.Mutex()
-	capTheorem.SimulateNetworkPartitionCP()
-	capTheorem.SimulateNetworkPartitionAP()
-	capTheorem.SimulateNetworkPartitionCA()
-	consistency.TwoPhaseCommit()
-	consistency.ProcessWithRedis()
-	resilience.GoCircuitBreake()*/
-	pratices.RunWebCrawler()
+	// The first SIGINT or SIGTERM cancels the root context so demos can wind down;
+	// a second one gets the default behaviour and kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	code := runCLI(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
 }
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Counters, gauges and histograms rendered in the Prometheus text exposition format,
+//without depending on the Prometheus client library or any external service.
+//Packages declare their metrics as package variables; they register themselves on Default,
+//the registry served on /metrics by the HTTP control plane.
+
+// Default is the registry the New* functions register on.
+var Default = NewRegistry()
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metric families by name.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// register panics on a duplicate name, since metric names are fixed at compile time
+func (r *Registry) register(f *family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.families[f.name]; dup {
+		panic("metrics: duplicate metric " + f.name)
+	}
+	r.families[f.name] = f
+}
+
+// WriteText writes every metric in the text exposition format, sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry on a /metrics endpoint.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// family is a metric name with one series per combination of label values
+type family struct {
+	name, help, kind string
+	labels           []string
+	buckets          []float64 // histograms only
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64  // counters and gauges
+	counts      []uint64 // histograms: observations per bucket, not cumulative
+	sum         float64
+	count       uint64
+}
+
+func newFamily(r *Registry, kind, name, help string, labels []string, buckets []float64) *family {
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	r.register(f)
+	return f
+}
+
+// get returns the series of the label values, creating it; f.mu must be held
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.buckets != nil {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) write(w io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := f.series[k]
+		if f.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelString(s.labelValues, "", ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelString(s.labelValues, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelString(s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labelString(s.labelValues, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labelString(s.labelValues, "", ""), s.count)
+	}
+}
+
+// labelString renders {a="1",b="2"}, with an optional extra label such as le
+func (f *family) labelString(values []string, extraName, extraValue string) string {
+	if len(values) == 0 && extraName == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, f.labels[i], escapeLabel(v)))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extraName, extraValue))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	jobs := r.NewCounter("jobs_processed_total", "Jobs processed by a worker.", "worker")
+	depth := r.NewGauge("queue_depth", "Jobs waiting in the queue.")
+	latency := r.NewHistogram("fetch_duration_seconds", "Page fetch latency.", []float64{0.1, 1})
+
+	jobs.Inc("1")
+	jobs.Add(2, "2")
+	jobs.Inc(`quo"te`)
+	depth.Set(3)
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(5)
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP fetch_duration_seconds Page fetch latency.
+# TYPE fetch_duration_seconds histogram
+fetch_duration_seconds_bucket{le="0.1"} 1
+fetch_duration_seconds_bucket{le="1"} 2
+fetch_duration_seconds_bucket{le="+Inf"} 3
+fetch_duration_seconds_sum 5.55
+fetch_duration_seconds_count 3
+# HELP jobs_processed_total Jobs processed by a worker.
+# TYPE jobs_processed_total counter
+jobs_processed_total{worker="1"} 1
+jobs_processed_total{worker="2"} 2
+jobs_processed_total{worker="quo\"te"} 1
+# HELP queue_depth Jobs waiting in the queue.
+# TYPE queue_depth gauge
+queue_depth 3
+`
+	if b.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
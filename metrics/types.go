@@ -0,0 +1,107 @@
+package metrics
+
+import "sort"
+
+// Counter is a value that only goes up, such as a number of processed jobs.
+type Counter struct{ f *family }
+
+// NewCounter registers a counter on Default. Every call to Inc or Add
+// must pass one value per label name, in order.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{f: newFamily(r, "counter", name, help, labels, nil)}
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter; negative values are ignored.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.get(labelValues).value += v
+}
+
+// Value returns the current value of a series.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	return c.f.get(labelValues).value
+}
+
+// Gauge is a value that goes up and down, such as a queue depth.
+type Gauge struct{ f *family }
+
+// NewGauge registers a gauge on Default.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{f: newFamily(r, "gauge", name, help, labels, nil)}
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.get(labelValues).value = v
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.get(labelValues).value += v
+}
+
+// Value returns the current value of a series.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	return g.f.get(labelValues).value
+}
+
+// Histogram counts observations, such as latencies, in cumulative buckets.
+type Histogram struct{ f *family }
+
+// NewHistogram registers a histogram on Default; nil buckets means DefBuckets.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Histogram{f: newFamily(r, "histogram", name, help, labels, buckets)}
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+
+	s := h.f.get(labelValues)
+	s.count++
+	s.sum += v
+	// The first bucket whose upper bound holds v; larger values only count in +Inf
+	i := sort.SearchFloat64s(h.f.buckets, v)
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+}
+
+// Count returns how many values a series observed.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	return h.f.get(labelValues).count
+}
@@ -2,25 +2,27 @@ package pratices
 
 import (
 	"context"
-	"fmt"
 	"time"
+
+	"GoBestPratices/clock"
+	"GoBestPratices/events"
 )
 
 // Why? Prevent goroutines from running indefinitely.
 // Key concept: Always call cancel() to free up resources.
-func Context() {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+func Context(ctx context.Context) error {
+	workCtx, cancel := clock.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
-	go doWork(ctx)
-	time.Sleep(3 * time.Second)
+	go doWork(workCtx)
+	return clock.Sleep(ctx, 3*time.Second)
 }
 
 func doWork(ctx context.Context) {
 	select {
-	case <-time.After(2 * time.Second):
-		fmt.Println("Work done")
+	case <-clock.FromContext(ctx).After(2 * time.Second):
+		events.Emit(ctx, events.WorkFinished{})
 	case <-ctx.Done():
-		fmt.Println("Work cancelled:", ctx.Err())
+		events.Emit(ctx, events.WorkFinished{Cancelled: true, Reason: ctx.Err().Error()})
 	}
 }
@@ -1,8 +1,11 @@
 package pratices
 
 import (
+	"context"
 	"errors"
 	"fmt"
+
+	"GoBestPratices/events"
 )
 
 //Prefer wrapping errors with %w in fmt.Errorf.
@@ -10,10 +13,10 @@ import (
 
 var ErrNotFound = errors.New("not found")
 
-func ErrorHandling() {
+func ErrorHandling(ctx context.Context) {
 	_, err := getUser(2)
 	if errors.Is(err, ErrNotFound) {
-		fmt.Println("User not found!")
+		events.Emit(ctx, events.Note{Text: "User not found!"})
 	}
 }
 
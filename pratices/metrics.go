@@ -0,0 +1,10 @@
+package pratices
+
+import "GoBestPratices/metrics"
+
+// Crawler metrics, exposed on /metrics
+var (
+	pagesFetched = metrics.NewCounter("crawler_pages_fetched_total", "Pages the web crawler fetched and parsed.")
+	pagesFailed  = metrics.NewCounter("crawler_pages_failed_total", "Pages the web crawler failed to fetch.")
+	fetchLatency = metrics.NewHistogram("crawler_fetch_duration_seconds", "Time to fetch and parse a page.", nil)
+)
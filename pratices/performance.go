@@ -2,17 +2,19 @@ package pratices
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"sync"
+
+	"GoBestPratices/events"
 )
 
 //Prefer sync.Pool for short-lived object reuse.
 //Use sync.Map instead of a mutex when high-concurrency read-heavy workloads.
 
-func Optimizing() {
+func Optimizing(ctx context.Context) {
 	buf := pool.Get().(*bytes.Buffer)
 	buf.WriteString("Hello, Pool!")
-	fmt.Println(buf.String())
+	events.Emit(ctx, events.Note{Text: buf.String()})
 	buf.Reset()
 	pool.Put(buf)
 }
@@ -0,0 +1,52 @@
+package pratices
+
+import (
+	"context"
+
+	"GoBestPratices/config"
+	"GoBestPratices/registry"
+)
+
+func init() {
+	registry.Register(registry.Example{
+		Name:        "context",
+		Category:    "practices",
+		Description: "Cancel work with context.WithTimeout",
+		Run: func(ctx context.Context, args registry.Args) error {
+			return Context(ctx)
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "error-handling",
+		Category:    "practices",
+		Description: "Wrap and match sentinel errors",
+		Run: func(ctx context.Context, args registry.Args) error {
+			ErrorHandling(ctx)
+			return nil
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "optimizing",
+		Category:    "practices",
+		Description: "Reuse buffers with sync.Pool",
+		Run: func(ctx context.Context, args registry.Args) error {
+			Optimizing(ctx)
+			return nil
+		},
+	})
+	registry.Register(registry.Example{
+		Name:        "web-crawler",
+		Category:    "practices",
+		Description: "Concurrent web crawler with a visited set",
+		Params: []registry.Param{
+			{Name: "url", Type: registry.String, Default: "https://www.example.ai", Usage: "URL to start crawling from", Key: "crawler.start_url"},
+			{Name: "depth", Type: registry.Int, Default: "3", Usage: "how many levels of links to follow", Key: "crawler.depth"},
+		},
+		Run: func(ctx context.Context, args registry.Args) error {
+			return RunWebCrawler(ctx, config.Crawler{
+				StartURL: args.String("url"),
+				Depth:    args.Int("depth"),
+			})
+		},
+	})
+}
@@ -1,14 +1,21 @@
 package pratices
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 
+	"GoBestPratices/clock"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+	"GoBestPratices/logging"
+	"GoBestPratices/tracing"
+
 	"golang.org/x/net/html"
 )
 
@@ -38,27 +45,38 @@ func (c *crawlQueue) Add(u string) bool {
 
 // Fetch and parse links from a given URL
 // fetchLinks fetches and parses the links (href attributes) from the given URL (baseURL)
-func fetchLinks(baseURL string) ([]string, error) {
+func fetchLinks(ctx context.Context, baseURL string) (links []string, err error) {
+	ctx, span := tracing.Start(ctx, "crawler.fetch", "url", baseURL)
+	defer func() {
+		span.SetAttributes("links", len(links))
+		span.RecordError(err)
+		span.End()
+	}()
+
 	// Send an HTTP GET request to the baseURL and store the response in resp.
 	// If there's an error (e.g., the URL is unreachable), return the error.
-	resp, err := http.Get(baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	span.SetAttributes("status_code", resp.StatusCode)
 
 	// Defer the closing of the response body (to ensure it gets closed when the function returns).
 	// The close function is wrapped in an anonymous function so that we can handle any potential error from closing.
 	defer func(Body io.ReadCloser) {
 		// Attempt to close the response body.
-		err := Body.Close()
-		// If there was an error closing the body, log it and terminate the program.
-		if err != nil {
-			log.Fatal("Error on Body.Close()")
+		// The links are already parsed, so a close error is only worth a warning.
+		if err := Body.Close(); err != nil {
+			logging.FromContext(ctx).Warn("closing response body", "err", err)
 		}
 	}(resp.Body)
 
 	// Initialize an empty slice to store the links we will find.
-	links := []string{}
+	links = []string{}
 
 	// Create a new HTML tokenizer that will allow us to parse the HTML from the response body.
 	tokenizer := html.NewTokenizer(resp.Body)
@@ -103,34 +121,52 @@ func fetchLinks(baseURL string) ([]string, error) {
 }
 
 // Crawl function
-func crawl(startURL string, depth int, queue *crawlQueue, wg *sync.WaitGroup) {
+func crawl(ctx context.Context, startURL string, depth int, queue *crawlQueue, wg *sync.WaitGroup) {
 	defer wg.Done()
-	if depth <= 0 || !queue.Add(startURL) {
+	// Stop following links once the crawl is cancelled
+	if depth <= 0 || ctx.Err() != nil || !queue.Add(startURL) {
 		return
 	}
 
-	fmt.Println("Crawling:", startURL)
-	links, err := fetchLinks(startURL)
+	ctx = logging.With(ctx, "url", startURL, "depth", depth)
+	ctx, span := tracing.Start(ctx, "crawler.crawl", "url", startURL, "depth", depth)
+	defer span.End()
+
+	logging.FromContext(ctx).Debug("fetching page")
+	start := clock.FromContext(ctx).Now()
+	links, err := fetchLinks(ctx, startURL)
+	fetchLatency.Observe(clock.FromContext(ctx).Since(start).Seconds())
 	if err != nil {
-		fmt.Println("Error fetching:", err)
+		pagesFailed.Inc()
+		logging.FromContext(ctx).Warn("fetching page failed", "err", err)
+		events.Emit(ctx, events.PageFailed{URL: startURL, Depth: depth, Err: err.Error()})
 		return
 	}
+	pagesFetched.Inc()
+	events.Emit(ctx, events.PageFetched{URL: startURL, Depth: depth, Links: len(links)})
 
 	for _, link := range links {
 		wg.Add(1)
-		go crawl(link, depth-1, queue, wg)
+		go crawl(ctx, link, depth-1, queue, wg)
 	}
 }
 
 // Main function
 // RunWebCrawler starts the crawling process for a given URL
-func RunWebCrawler() {
-	// The starting URL to begin the crawl from
-	startURL := "https://www.example.ai"
+func RunWebCrawler(ctx context.Context, cfg config.Crawler) error {
+	startURL, depth := cfg.StartURL, cfg.Depth
+
+	// The starting URL to begin the crawl from must be an absolute http(s) URL
+	parsedURL, err := url.Parse(startURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return fmt.Errorf("invalid start URL %q", startURL)
+	}
 
 	// The depth of the crawl: how many levels deep we want to crawl
 	// Depth 2 means it will crawl the startURL and links found on that page (i.e., first 2 levels)
-	depth := 3
+	if depth <= 0 {
+		return errors.New("depth must be greater than zero")
+	}
 
 	// Initialize a new crawlQueue to track visited URLs
 	// This queue will keep track of which URLs have already been visited to prevent revisiting them
@@ -143,11 +179,12 @@ func RunWebCrawler() {
 	wg.Add(1)
 
 	// Start the crawl process in a new goroutine.
-	// `go crawl(startURL, depth, queue, &wg)` initiates the crawl concurrently,
+	// `go crawl(ctx, startURL, depth, queue, &wg)` initiates the crawl concurrently,
 	// which means the crawl won't block the main thread, and other tasks can run in parallel if needed.
-	go crawl(startURL, depth, queue, &wg)
+	go crawl(ctx, startURL, depth, queue, &wg)
 
 	// Wait for all goroutines (in this case, the initial crawl goroutine) to complete
 	// This ensures that the program won't exit until the crawling process finishes
 	wg.Wait()
+	return ctx.Err()
 }
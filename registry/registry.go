@@ -0,0 +1,196 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//The registry is the single place where demos are discovered.
+//Every package registers its examples from an init function, the same way database/sql drivers do,
+//so the CLI, the tests and any server can enumerate and run them without hard-coded calls.
+
+// ErrUnknownExample is returned when no example is registered under a name.
+var ErrUnknownExample = errors.New("unknown example")
+
+// ParamType is the type of value a parameter accepts.
+type ParamType string
+
+const (
+	String   ParamType = "string"
+	Int      ParamType = "int"
+	Float    ParamType = "float"
+	Bool     ParamType = "bool"
+	Duration ParamType = "duration"
+)
+
+// Param describes one parameter accepted by an example.
+// Key optionally binds the parameter to a configuration key, which then provides its default.
+// Choices optionally lists the only values the parameter accepts.
+type Param struct {
+	Name    string    `json:"name"`
+	Type    ParamType `json:"type"`
+	Default string    `json:"default"`
+	Usage   string    `json:"usage"`
+	Key     string    `json:"key,omitempty"`
+	Choices []string  `json:"choices,omitempty"`
+}
+
+// Example is a runnable demo with its metadata.
+type Example struct {
+	Name        string                                     `json:"name"`
+	Category    string                                     `json:"category"`
+	Description string                                     `json:"description"`
+	Params      []Param                                    `json:"params,omitempty"`
+	Run         func(ctx context.Context, args Args) error `json:"-"`
+}
+
+var (
+	mu       sync.RWMutex
+	examples = make(map[string]Example)
+)
+
+// Register makes an example available by name. It panics on a duplicate name
+// or an invalid parameter default, since both are programming errors.
+func Register(e Example) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if e.Name == "" || e.Run == nil {
+		panic("registry: example needs a name and a Run function")
+	}
+	if _, dup := examples[e.Name]; dup {
+		panic("registry: Register called twice for example " + e.Name)
+	}
+	for _, p := range e.Params {
+		if err := validate(p, p.Default); err != nil {
+			panic(fmt.Sprintf("registry: example %s: %v", e.Name, err))
+		}
+	}
+	examples[e.Name] = e
+}
+
+// All returns every registered example sorted by category and name.
+func All() []Example {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	all := make([]Example, 0, len(examples))
+	for _, e := range examples {
+		all = append(all, e)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Category != all[j].Category {
+			return all[i].Category < all[j].Category
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// Lookup returns the example registered under name.
+func Lookup(name string) (Example, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := examples[name]
+	return e, ok
+}
+
+// Run looks up an example, validates the given values against its parameters and runs it.
+func Run(ctx context.Context, name string, values map[string]string) error {
+	e, ok := Lookup(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownExample, name)
+	}
+	args, err := e.Parse(values)
+	if err != nil {
+		return err
+	}
+	return e.Run(ctx, args)
+}
+
+// Parse validates the given values against the example parameters and fills in the defaults.
+func (e Example) Parse(values map[string]string) (Args, error) {
+	args := make(Args, len(e.Params))
+	for _, p := range e.Params {
+		args[p.Name] = p.Default
+	}
+	for name, v := range values {
+		p, ok := e.param(name)
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown parameter %q", e.Name, name)
+		}
+		if err := validate(p, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name, err)
+		}
+		args[name] = v
+	}
+	return args, nil
+}
+
+func (e Example) param(name string) (Param, bool) {
+	for _, p := range e.Params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+func validate(p Param, v string) error {
+	var err error
+	switch p.Type {
+	case String:
+	case Int:
+		_, err = strconv.Atoi(v)
+	case Float:
+		_, err = strconv.ParseFloat(v, 64)
+	case Bool:
+		_, err = strconv.ParseBool(v)
+	case Duration:
+		_, err = time.ParseDuration(v)
+	default:
+		return fmt.Errorf("parameter %q has unknown type %q", p.Name, p.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("parameter %q: invalid %s %q", p.Name, p.Type, v)
+	}
+	if len(p.Choices) > 0 && !slices.Contains(p.Choices, v) {
+		return fmt.Errorf("parameter %q: %q is not one of %s", p.Name, v, strings.Join(p.Choices, ", "))
+	}
+	return nil
+}
+
+// Args holds the validated parameter values of a run.
+// The typed getters never fail because Parse already validated every value.
+type Args map[string]string
+
+func (a Args) String(name string) string {
+	return a[name]
+}
+
+func (a Args) Int(name string) int {
+	v, _ := strconv.Atoi(a[name])
+	return v
+}
+
+func (a Args) Float(name string) float64 {
+	v, _ := strconv.ParseFloat(a[name], 64)
+	return v
+}
+
+func (a Args) Bool(name string) bool {
+	v, _ := strconv.ParseBool(a[name])
+	return v
+}
+
+func (a Args) Duration(name string) time.Duration {
+	v, _ := time.ParseDuration(a[name])
+	return v
+}
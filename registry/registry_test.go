@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	e := Example{
+		Name: "test",
+		Params: []Param{
+			{Name: "depth", Type: Int, Default: "3"},
+			{Name: "wait", Type: Duration, Default: "1s"},
+			{Name: "engine", Type: String, Default: "raft", Choices: []string{"raft", "paxos"}},
+		},
+		Run: func(ctx context.Context, args Args) error { return nil },
+	}
+
+	tests := []struct {
+		values  map[string]string
+		depth   int
+		wait    time.Duration
+		wantErr bool
+	}{
+		{values: nil, depth: 3, wait: time.Second},
+		{values: map[string]string{"depth": "5"}, depth: 5, wait: time.Second},
+		{values: map[string]string{"wait": "250ms"}, depth: 3, wait: 250 * time.Millisecond},
+		{values: map[string]string{"depth": "deep"}, wantErr: true},
+		{values: map[string]string{"unknown": "1"}, wantErr: true},
+		{values: map[string]string{"engine": "paxos"}, depth: 3, wait: time.Second},
+		{values: map[string]string{"engine": "pax"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		args, err := e.Parse(tt.values)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%v) succeeded; want error", tt.values)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%v) = %v", tt.values, err)
+			continue
+		}
+		if args.Int("depth") != tt.depth || args.Duration("wait") != tt.wait {
+			t.Errorf("Parse(%v) = %v; want depth %d wait %v", tt.values, args, tt.depth, tt.wait)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"GoBestPratices/clock"
+)
+
+//The breaker follows the state machine of sony/gobreaker, but reads the time from a clock.Clock,
+//so a Fake clock drives its Interval and Timeout like every other sleep and deadline of the demos.
+//Closed: calls go through, and the counts are cleared every Interval; ReadyToTrip opens the breaker after a failure.
+//Open: calls are refused until Timeout has passed, then the breaker is half-open.
+//Half-open: up to MaxRequests calls go through; that many successes close the breaker, one failure opens it again.
+
+var (
+	// ErrOpenState is returned when the breaker is open.
+	ErrOpenState = errors.New("circuit breaker is open")
+	// ErrTooManyRequests is returned when the breaker is half-open and already let MaxRequests calls through.
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	return [...]string{"closed", "half-open", "open"}[s]
+}
+
+// Counts holds the outcomes of the calls since the counts were last cleared.
+type Counts struct {
+	Requests             uint32
+	TotalSuccesses       uint32
+	TotalFailures        uint32
+	ConsecutiveSuccesses uint32
+	ConsecutiveFailures  uint32
+}
+
+// BreakerSettings configures a Breaker; the zero values of MaxRequests, Timeout and ReadyToTrip
+// mean 1 call, 60 seconds and more than 5 consecutive failures, as in gobreaker.
+type BreakerSettings struct {
+	Name        string
+	MaxRequests uint32
+	// Interval is how often a closed breaker clears its counts, never if 0
+	Interval time.Duration
+	// Timeout is how long the breaker stays open before letting calls through again
+	Timeout       time.Duration
+	ReadyToTrip   func(Counts) bool
+	OnStateChange func(name string, from, to State)
+}
+
+// Breaker is a circuit breaker whose transitions follow the time of a clock.Clock. It is safe for concurrent use.
+type Breaker struct {
+	settings BreakerSettings
+	clock    clock.Clock
+
+	mu     sync.Mutex
+	state  State
+	counts Counts
+	// generation changes with the counts, so the outcome of a call started before is ignored
+	generation uint64
+	// expiry is when a closed breaker clears its counts, or an open one becomes half-open; zero for never
+	expiry time.Time
+}
+
+// NewBreaker returns a closed breaker reading the time from c.
+func NewBreaker(s BreakerSettings, c clock.Clock) *Breaker {
+	if s.MaxRequests == 0 {
+		s.MaxRequests = 1
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = 60 * time.Second
+	}
+	if s.ReadyToTrip == nil {
+		s.ReadyToTrip = func(counts Counts) bool { return counts.ConsecutiveFailures > 5 }
+	}
+	b := &Breaker{settings: s, clock: c}
+	b.setState(StateClosed, c.Now())
+	return b
+}
+
+// State returns the state of the breaker now.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(b.clock.Now())
+	return b.state
+}
+
+// Execute runs req if the breaker lets it through, and records its outcome.
+func (b *Breaker) Execute(req func() (any, error)) (any, error) {
+	generation, err := b.beforeRequest()
+	if err != nil {
+		return nil, err
+	}
+	result, err := req()
+	b.afterRequest(generation, err == nil)
+	return result, err
+}
+
+func (b *Breaker) beforeRequest() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh(b.clock.Now())
+	switch {
+	case b.state == StateOpen:
+		return 0, ErrOpenState
+	case b.state == StateHalfOpen && b.counts.Requests >= b.settings.MaxRequests:
+		return 0, ErrTooManyRequests
+	}
+	b.counts.Requests++
+	return b.generation, nil
+}
+
+func (b *Breaker) afterRequest(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	b.refresh(now)
+	if generation != b.generation {
+		return
+	}
+	if success {
+		b.onSuccess(now)
+	} else {
+		b.onFailure(now)
+	}
+}
+
+func (b *Breaker) onSuccess(now time.Time) {
+	b.counts.TotalSuccesses++
+	b.counts.ConsecutiveSuccesses++
+	b.counts.ConsecutiveFailures = 0
+	if b.state == StateHalfOpen && b.counts.ConsecutiveSuccesses >= b.settings.MaxRequests {
+		b.setState(StateClosed, now)
+	}
+}
+
+func (b *Breaker) onFailure(now time.Time) {
+	b.counts.TotalFailures++
+	b.counts.ConsecutiveFailures++
+	b.counts.ConsecutiveSuccesses = 0
+	if b.state == StateHalfOpen || b.settings.ReadyToTrip(b.counts) {
+		b.setState(StateOpen, now)
+	}
+}
+
+// refresh clears the counts of a closed breaker whose interval ended, and half-opens an open one whose timeout did.
+func (b *Breaker) refresh(now time.Time) {
+	if b.expiry.IsZero() || now.Before(b.expiry) {
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		b.newGeneration(now)
+	case StateOpen:
+		b.setState(StateHalfOpen, now)
+	}
+}
+
+func (b *Breaker) setState(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.newGeneration(now)
+	if from != to && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(b.settings.Name, from, to)
+	}
+}
+
+// newGeneration clears the counts and sets when the current state expires.
+func (b *Breaker) newGeneration(now time.Time) {
+	b.generation++
+	b.counts = Counts{}
+	b.expiry = time.Time{}
+	switch {
+	case b.state == StateClosed && b.settings.Interval > 0:
+		b.expiry = now.Add(b.settings.Interval)
+	case b.state == StateOpen:
+		b.expiry = now.Add(b.settings.Timeout)
+	}
+}
@@ -0,0 +1,99 @@
+package resilience
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"GoBestPratices/clock"
+)
+
+var errCall = errors.New("call failed")
+
+func call(b *Breaker, err error) error {
+	_, got := b.Execute(func() (any, error) { return nil, err })
+	return got
+}
+
+func TestBreakerFollowsFakeClock(t *testing.T) {
+	f := clock.NewFake(time.Unix(0, 0))
+	var transitions []string
+	b := NewBreaker(BreakerSettings{
+		Name:        "test",
+		MaxRequests: 2,
+		Timeout:     10 * time.Second,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures > 1 },
+		OnStateChange: func(_ string, from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	}, f)
+
+	call(b, errCall)
+	call(b, errCall)
+	if b.State() != StateOpen {
+		t.Fatalf("State() = %v after 2 failures; want open", b.State())
+	}
+	if err := call(b, nil); !errors.Is(err, ErrOpenState) {
+		t.Fatalf("Execute on an open breaker = %v; want %v", err, ErrOpenState)
+	}
+
+	f.Advance(9 * time.Second)
+	if b.State() != StateOpen {
+		t.Fatalf("State() = %v before the timeout; want open", b.State())
+	}
+	f.Advance(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("State() = %v after the timeout; want half-open", b.State())
+	}
+
+	call(b, nil)
+	call(b, nil)
+	if b.State() != StateClosed {
+		t.Fatalf("State() = %v after %d successes in half-open; want closed", b.State(), 2)
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if !slices.Equal(transitions, want) {
+		t.Errorf("transitions = %v; want %v", transitions, want)
+	}
+}
+
+func TestBreakerHalfOpenLimitsRequests(t *testing.T) {
+	f := clock.NewFake(time.Unix(0, 0))
+	b := NewBreaker(BreakerSettings{
+		MaxRequests: 1,
+		Timeout:     time.Second,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures > 0 },
+	}, f)
+
+	call(b, errCall)
+	f.Advance(time.Second)
+	var inner error
+	b.Execute(func() (any, error) {
+		inner = call(b, nil)
+		return nil, errCall
+	})
+	if !errors.Is(inner, ErrTooManyRequests) {
+		t.Errorf("second call while half-open = %v; want %v", inner, ErrTooManyRequests)
+	}
+	if b.State() != StateOpen {
+		t.Errorf("State() = %v after a failure in half-open; want open", b.State())
+	}
+}
+
+func TestBreakerIntervalClearsCounts(t *testing.T) {
+	f := clock.NewFake(time.Unix(0, 0))
+	b := NewBreaker(BreakerSettings{Interval: time.Minute,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures > 1 }}, f)
+
+	call(b, errCall)
+	f.Advance(time.Minute)
+	call(b, errCall)
+	if b.State() != StateClosed {
+		t.Errorf("State() = %v; want closed, the interval cleared the first failure", b.State())
+	}
+	call(b, errCall)
+	if b.State() != StateOpen {
+		t.Errorf("State() = %v after 2 failures in one interval; want open", b.State())
+	}
+}
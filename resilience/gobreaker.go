@@ -1,52 +1,67 @@
 package resilience
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
 
-	"github.com/sony/gobreaker"
+	"GoBestPratices/clock"
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+	"GoBestPratices/logging"
 )
 
 //A Circuit Breaker is a useful pattern for preventing cascading failures in a distributed system, especially when services rely on external systems or APIs.
 //It helps to "break" the connection if the failure threshold is met and prevents the system from trying to call a failing service repeatedly, thus reducing the load on the failing service and allowing it to recover.
+//The breaker (breaker.go) behaves like sony/gobreaker but takes its time from the clock in the context, so tests can drive it with a Fake clock.
 
-func GoCircuitBreake() {
+func GoCircuitBreake(ctx context.Context, cfg config.Breaker) error {
+	ctx = logging.With(ctx, "breaker", cfg.Name)
 	// Configure the circuit breaker
-	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
-		Name:        "ExampleCircuitBreaker",
-		MaxRequests: 5,                // Maximum number of requests allowed before the circuit is checked
-		Interval:    60 * time.Second, // How long the circuit breaker will stay open
-		Timeout:     10 * time.Second, // Time to wait for the external service before considering it failed
-		ReadyToTrip: func(counts gobreaker.Counts) bool {
-			return counts.ConsecutiveFailures > 2 // Break the circuit after 2 consecutive failures
+	cb := NewBreaker(BreakerSettings{
+		Name:        cfg.Name,
+		MaxRequests: cfg.MaxRequests, // Maximum number of requests allowed before the circuit is checked
+		Interval:    cfg.Interval,    // How long the circuit breaker will stay open
+		Timeout:     cfg.Timeout,     // Time to wait for the external service before considering it failed
+		ReadyToTrip: func(counts Counts) bool {
+			return counts.ConsecutiveFailures > cfg.FailureThreshold // Break the circuit after too many consecutive failures
 		},
-	})
+		OnStateChange: func(name string, from, to State) {
+			breakerTransitions.Inc(name, from.String(), to.String())
+			logging.FromContext(ctx).Info("circuit breaker state changed", "from", from.String(), "to", to.String())
+			events.Emit(ctx, events.BreakerStateChanged{Name: name, From: from.String(), To: to.String()})
+		},
+	}, clock.FromContext(ctx))
 
 	// Simulate multiple calls to the unreliable API
-	for i := 0; i < 10; i++ {
-		fmt.Printf("Attempt #%d...\n", i+1)
+	for i := 0; i < cfg.Attempts; i++ {
+		attempt := i + 1
+		logging.FromContext(ctx).Debug("calling API", "attempt", attempt)
 
 		// Use the circuit breaker to make the API call
-		result, err := cb.Execute(func() (interface{}, error) {
+		_, err := cb.Execute(func() (any, error) {
 			err := unreliableAPI()
 			if err != nil {
-				fmt.Println("API call failed:", err)
+				events.Emit(ctx, events.CallFailed{Attempt: attempt, Err: err.Error()})
 				return nil, err // Return `nil` for the result and the error
 			}
-			fmt.Println("API call succeeded")
+			events.Emit(ctx, events.CallSucceeded{Attempt: attempt})
 			return "Success", nil // Return a valid result and `nil` for the error
 		})
 
-		if err != nil {
-			fmt.Println("Error:", err)
-		} else {
-			// This part will be triggered if the function executes successfully.
-			fmt.Println("Result:", result)
+		// The breaker refused the call without reaching the API
+		if errors.Is(err, ErrOpenState) || errors.Is(err, ErrTooManyRequests) {
+			breakerRejected.Inc(cfg.Name)
+			events.Emit(ctx, events.CallRejected{Attempt: attempt, Reason: err.Error()})
 		}
 
-		time.Sleep(1 * time.Second) // Simulate time between requests
+		if err := clock.Sleep(ctx, 1*time.Second); err != nil { // Simulate time between requests
+			return err
+		}
 	}
+	return nil
 }
 
 // Simulated API call that may fail
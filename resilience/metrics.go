@@ -0,0 +1,9 @@
+package resilience
+
+import "GoBestPratices/metrics"
+
+// Circuit breaker metrics, exposed on /metrics
+var (
+	breakerTransitions = metrics.NewCounter("circuit_breaker_state_transitions_total", "Circuit breaker state changes.", "breaker", "from", "to")
+	breakerRejected    = metrics.NewCounter("circuit_breaker_rejected_calls_total", "Calls refused by an open or half-open circuit breaker.", "breaker")
+)
@@ -0,0 +1,34 @@
+package resilience
+
+import (
+	"context"
+
+	"GoBestPratices/config"
+	"GoBestPratices/registry"
+)
+
+func init() {
+	registry.Register(registry.Example{
+		Name:        "circuit-breaker",
+		Category:    "resilience",
+		Description: "Circuit breaker around an unreliable API",
+		Params: []registry.Param{
+			{Name: "name", Type: registry.String, Default: "ExampleCircuitBreaker", Usage: "circuit breaker name", Key: "breaker.name"},
+			{Name: "max-requests", Type: registry.Int, Default: "5", Usage: "requests allowed while half-open", Key: "breaker.max_requests"},
+			{Name: "interval", Type: registry.Duration, Default: "1m0s", Usage: "period after which closed counts are cleared", Key: "breaker.interval"},
+			{Name: "timeout", Type: registry.Duration, Default: "10s", Usage: "how long the breaker stays open", Key: "breaker.timeout"},
+			{Name: "failure-threshold", Type: registry.Int, Default: "2", Usage: "consecutive failures tolerated before tripping", Key: "breaker.failure_threshold"},
+			{Name: "attempts", Type: registry.Int, Default: "10", Usage: "number of API calls to attempt", Key: "breaker.attempts"},
+		},
+		Run: func(ctx context.Context, args registry.Args) error {
+			return GoCircuitBreake(ctx, config.Breaker{
+				Name:             args.String("name"),
+				MaxRequests:      uint32(args.Int("max-requests")),
+				Interval:         args.Duration("interval"),
+				Timeout:          args.Duration("timeout"),
+				FailureThreshold: uint32(args.Int("failure-threshold")),
+				Attempts:         args.Int("attempts"),
+			})
+		},
+	})
+}
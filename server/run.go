@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"GoBestPratices/events"
+	"GoBestPratices/logging"
+	"GoBestPratices/registry"
+	"GoBestPratices/tracing"
+)
+
+// Status of a run as reported by GET /runs/{id}.
+type Status struct {
+	ID     string `json:"id"`
+	Demo   string `json:"demo"`
+	State  string `json:"state"` // running, succeeded, failed or cancelled
+	Error  string `json:"error,omitempty"`
+	Events int    `json:"events"`
+}
+
+// run is one execution of a demo. It is the event sink of that execution and
+// keeps every record, so late subscribers replay the stream from the start.
+type run struct {
+	id, demo string
+	spans    *tracing.InMemoryExporter
+
+	mu      sync.Mutex
+	records []events.JSONRecord
+	done    bool
+	err     error
+	changed chan struct{} // closed and replaced on every new record
+}
+
+func newRun(id, demo string) *run {
+	return &run{id: id, demo: demo, spans: &tracing.InMemoryExporter{}, changed: make(chan struct{})}
+}
+
+func (rn *run) start(ctx context.Context, e registry.Example, args registry.Args) {
+	ctx = logging.With(ctx, "run_id", rn.id, "demo", rn.demo)
+	ctx = tracing.WithTracer(ctx, tracing.NewTracer(rn.spans))
+	logger := logging.FromContext(ctx)
+	logger.Info("run started")
+
+	err := e.Run(events.WithSink(ctx, rn), args)
+	if err != nil {
+		logger.Error("run failed", "err", err)
+	} else {
+		logger.Info("run succeeded")
+	}
+
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.done = true
+	rn.err = err
+	rn.notify()
+}
+
+func (rn *run) Emit(r events.Record) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.records = append(rn.records, events.Marshal(r))
+	rn.notify()
+}
+
+// since returns the records after the first n, whether the run is over,
+// and a channel closed when anything changes.
+func (rn *run) since(n int) ([]events.JSONRecord, bool, <-chan struct{}) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	return append([]events.JSONRecord(nil), rn.records[n:]...), rn.done, rn.changed
+}
+
+func (rn *run) status() Status {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	st := Status{ID: rn.id, Demo: rn.demo, State: "running", Events: len(rn.records)}
+	switch {
+	case rn.done && errors.Is(rn.err, context.Canceled):
+		st.State, st.Error = "cancelled", rn.err.Error()
+	case rn.done && rn.err != nil:
+		st.State, st.Error = "failed", rn.err.Error()
+	case rn.done:
+		st.State = "succeeded"
+	}
+	return st
+}
+
+// notify wakes the event streams; rn.mu must be held
+func (rn *run) notify() {
+	close(rn.changed)
+	rn.changed = make(chan struct{})
+}
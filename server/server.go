@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/metrics"
+	"GoBestPratices/registry"
+)
+
+//Server is a small HTTP control plane over the registry:
+//GET  /demos              lists the available demos with their parameters
+//POST /runs               starts a demo: {"demo": "two-phase-commit", "params": {"amount": 200}}
+//GET  /runs/{id}          returns the status of a run
+//GET  /runs/{id}/events   streams the events of a run as Server-Sent Events, from the first one
+//GET  /runs/{id}/trace    returns the trace spans the run finished so far
+//GET  /metrics            exposes the metrics of every demo in the Prometheus text format
+//Runs are kept until they finish, then only the last maxFinishedRuns finished runs are: older ones answer 404.
+
+// maxFinishedRuns is how many finished runs, with their events and spans, the server keeps in memory.
+const maxFinishedRuns = 100
+
+type Server struct {
+	ctx context.Context // parent of every run started by the server
+	cfg config.Config   // provides the parameter defaults of every demo
+
+	mu       sync.Mutex
+	nextID   int
+	runs     map[string]*run
+	finished []string // IDs of the finished runs still kept, oldest first
+	keep     int      // how many finished runs to keep
+}
+
+// New returns a server whose runs inherit ctx, including its clock and cancellation,
+// and take their parameter defaults from cfg.
+func New(ctx context.Context, cfg config.Config) *Server {
+	return &Server{ctx: ctx, cfg: cfg, runs: make(map[string]*run), keep: maxFinishedRuns}
+}
+
+// Handler returns the HTTP routes of the control plane.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /demos", s.listDemos)
+	mux.HandleFunc("POST /runs", s.startRun)
+	mux.HandleFunc("GET /runs/{id}", s.runStatus)
+	mux.HandleFunc("GET /runs/{id}/events", s.streamEvents)
+	mux.HandleFunc("GET /runs/{id}/trace", s.runTrace)
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+	return mux
+}
+
+// ListenAndServe serves the control plane on addr until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func (s *Server) listDemos(w http.ResponseWriter, r *http.Request) {
+	all := registry.All()
+	for i, e := range all {
+		all[i] = s.cfg.Apply(e)
+	}
+	writeJSON(w, http.StatusOK, all)
+}
+
+type startRequest struct {
+	Demo   string         `json:"demo"`
+	Params map[string]any `json:"params"`
+}
+
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request) {
+	var req startRequest
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber() // keep numbers as written, so "10" and 10 validate the same way
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	e, ok := registry.Lookup(req.Demo)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", registry.ErrUnknownExample, req.Demo))
+		return
+	}
+	e = s.cfg.Apply(e)
+	values := make(map[string]string, len(req.Params))
+	for name, v := range req.Params {
+		values[name] = fmt.Sprint(v)
+	}
+	args, err := e.Parse(values)
+	if err == nil {
+		err = s.cfg.ValidateArgs(e, args)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	rn := newRun(id, e.Name)
+	s.runs[id] = rn
+	s.mu.Unlock()
+
+	go func() {
+		rn.start(s.ctx, e, args)
+		s.finish(id)
+	}()
+
+	w.Header().Set("Location", "/runs/"+id)
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"id":     id,
+		"status": "/runs/" + id,
+		"events": "/runs/" + id + "/events",
+	})
+}
+
+// finish records that run id is over and forgets the oldest finished runs beyond s.keep.
+func (s *Server) finish(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = append(s.finished, id)
+	for len(s.finished) > s.keep {
+		delete(s.runs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+func (s *Server) lookupRun(w http.ResponseWriter, r *http.Request) (*run, bool) {
+	s.mu.Lock()
+	rn, ok := s.runs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown run "+r.PathValue("id")))
+	}
+	return rn, ok
+}
+
+func (s *Server) runStatus(w http.ResponseWriter, r *http.Request) {
+	if rn, ok := s.lookupRun(w, r); ok {
+		writeJSON(w, http.StatusOK, rn.status())
+	}
+}
+
+func (s *Server) runTrace(w http.ResponseWriter, r *http.Request) {
+	if rn, ok := s.lookupRun(w, r); ok {
+		writeJSON(w, http.StatusOK, rn.spans.Spans())
+	}
+}
+
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	rn, ok := s.lookupRun(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sent := 0
+	for {
+		records, done, changed := rn.since(sent)
+		for _, rec := range records {
+			sent++
+			writeSSE(w, strconv.Itoa(sent), rec.Kind, rec)
+		}
+		if done {
+			writeSSE(w, "", "done", rn.status())
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSE writes one Server-Sent Event; data is always a single line of JSON
+func writeSSE(w http.ResponseWriter, id, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"GoBestPratices/config"
+	"GoBestPratices/events"
+	"GoBestPratices/registry"
+)
+
+func init() {
+	registry.Register(registry.Example{
+		Name:     "server-test-echo",
+		Category: "test",
+		Params:   []registry.Param{{Name: "times", Type: registry.Int, Default: "1"}},
+		Run: func(ctx context.Context, args registry.Args) error {
+			for i := 0; i < args.Int("times"); i++ {
+				events.Emit(ctx, events.Note{Text: "echo"})
+			}
+			return nil
+		},
+	})
+}
+
+func TestRunStreamsEvents(t *testing.T) {
+	srv := httptest.NewServer(New(context.Background(), config.Default()).Handler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/runs", "application/json",
+		strings.NewReader(`{"demo": "server-test-echo", "params": {"times": 3}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var started map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&started); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /runs = %d; want %d", resp.StatusCode, http.StatusAccepted)
+	}
+
+	stream, err := http.Get(srv.URL + started["events"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+
+	var kinds []string
+	scanner := bufio.NewScanner(stream.Body)
+	for scanner.Scan() {
+		if kind, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	want := []string{"note", "note", "note", "done"}
+	if strings.Join(kinds, ",") != strings.Join(want, ",") {
+		t.Errorf("streamed events %v; want %v", kinds, want)
+	}
+}
+
+func TestStartRunValidatesRequest(t *testing.T) {
+	srv := httptest.NewServer(New(context.Background(), config.Default()).Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"demo": "does-not-exist"}`, http.StatusNotFound},
+		{`{"demo": "server-test-echo", "params": {"times": "many"}}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Post(srv.URL+"/runs", "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("POST /runs %s = %d; want %d", tt.body, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestFinishedRunsAreEvicted(t *testing.T) {
+	s := New(context.Background(), config.Default())
+	s.keep = 2
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	for i := 0; i < 4; i++ {
+		resp, err := http.Post(srv.URL+"/runs", "application/json", strings.NewReader(`{"demo": "server-test-echo"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		kept := len(s.runs)
+		s.mu.Unlock()
+		if kept == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server keeps %d runs; want the last 2 finished", kept)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The runs finish in any order: whichever finished first are gone
+	gone := 0
+	for _, id := range []string{"1", "2", "3", "4"} {
+		resp, err := http.Get(srv.URL + "/runs/" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			gone++
+		}
+	}
+	if gone != 2 {
+		t.Errorf("%d runs answer %d after eviction; want 2", gone, http.StatusNotFound)
+	}
+}
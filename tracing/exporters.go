@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// InMemoryExporter keeps every finished span, for tests and the HTTP control plane.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *InMemoryExporter) Export(s SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, s)
+}
+
+// Spans returns the finished spans in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// JSONFileExporter appends every finished span to a file, one JSON object per line.
+// Spans are buffered: the file is only complete once Close returns.
+type JSONFileExporter struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONFileExporter creates or truncates path.
+func NewJSONFileExporter(path string) (*JSONFileExporter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating trace file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	return &JSONFileExporter{f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (e *JSONFileExporter) Export(s SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err == nil {
+		e.err = e.enc.Encode(s)
+	}
+}
+
+// Close flushes the file and returns the first write error, if any.
+func (e *JSONFileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.w.Flush(); e.err == nil {
+		e.err = err
+	}
+	if err := e.f.Close(); e.err == nil {
+		e.err = err
+	}
+	return e.err
+}
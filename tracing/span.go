@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"GoBestPratices/clock"
+)
+
+// Span is a timed operation in a trace. All methods are safe on a nil span.
+type Span struct {
+	tracer *Tracer
+	clock  clock.Clock
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SetAttributes adds key/value pairs to the span.
+func (s *Span) SetAttributes(kv ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil && len(kv) > 0 {
+		s.data.Attributes = make(map[string]any)
+	}
+	addPairs(s.data.Attributes, kv)
+}
+
+// AddEvent records a named event with optional key/value pairs.
+func (s *Span) AddEvent(name string, kv ...any) {
+	if s == nil {
+		return
+	}
+	e := Event{Name: name, Time: s.clock.Now()}
+	if len(kv) > 0 {
+		e.Attributes = make(map[string]any)
+		addPairs(e.Attributes, kv)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Events = append(s.data.Events, e)
+}
+
+// RecordError marks the span as failed; nil errors are ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End stamps the span and exports it. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = s.clock.Now()
+	s.data.Duration = s.data.End.Sub(s.data.Start)
+	// The exported copy must not change if the span is still written to after End
+	data := s.data
+	data.Attributes = maps.Clone(s.data.Attributes)
+	data.Events = slices.Clone(s.data.Events)
+	s.mu.Unlock()
+
+	s.tracer.exporter.Export(data)
+}
+
+// addPairs stores alternating keys and values; a trailing key gets a nil value
+func addPairs(m map[string]any, kv []any) {
+	for i := 0; i < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		var v any
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		m[key] = v
+	}
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"GoBestPratices/clock"
+)
+
+//A lightweight in-process tracer: spans form a tree through the context, carry attributes
+//and timestamped events, and are handed to an Exporter when they end.
+//Without a tracer in the context Start returns a nil span, and every Span method is a no-op on nil,
+//so instrumented code never has to check whether tracing is enabled.
+
+// SpanData is the exported, immutable view of a finished span.
+type SpanData struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Name       string         `json:"name"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Duration   time.Duration  `json:"duration_ns"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Events     []Event        `json:"events,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Event is something that happened at a point in time during a span.
+type Event struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Exporter receives every span when it ends. Implementations must be safe for concurrent use.
+type Exporter interface {
+	Export(s SpanData)
+}
+
+// Tracer creates spans and sends them to its exporter.
+type Tracer struct {
+	exporter Exporter
+	traceSeq atomic.Uint64
+	spanSeq  atomic.Uint64
+	prefix   uint32 // distinguishes the ids of different tracers
+}
+
+func NewTracer(exp Exporter) *Tracer {
+	return &Tracer{exporter: exp, prefix: rand.Uint32()}
+}
+
+type tracerKey struct{}
+type spanKey struct{}
+
+// WithTracer returns a context in which Start records spans with t.
+func WithTracer(ctx context.Context, t *Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// SpanFromContext returns the current span, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Start begins a span named name, child of the span in ctx if any.
+// attrs are key/value pairs, like slog: "url", u, "depth", 2.
+// The returned context carries the new span; call End when the work is done.
+func Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	t, ok := ctx.Value(tracerKey{}).(*Tracer)
+	if !ok {
+		return ctx, nil
+	}
+
+	c := clock.FromContext(ctx)
+	s := &Span{tracer: t, clock: c}
+	s.data.Name = name
+	s.data.Start = c.Now()
+	s.data.SpanID = fmt.Sprintf("%08x%08x", t.prefix, t.spanSeq.Add(1))
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.data.TraceID = parent.data.TraceID
+		s.data.ParentID = parent.data.SpanID
+	} else {
+		s.data.TraceID = fmt.Sprintf("%08x%016x", t.prefix, t.traceSeq.Add(1))
+	}
+	s.SetAttributes(attrs...)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
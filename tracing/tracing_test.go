@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"GoBestPratices/clock"
+)
+
+func TestSpansFormATree(t *testing.T) {
+	exp := &InMemoryExporter{}
+	fake := clock.NewFake(time.Unix(0, 0))
+	ctx := WithTracer(clock.WithClock(context.Background(), fake), NewTracer(exp))
+
+	ctx, root := Start(ctx, "transaction", "tx_id", 1)
+	_, child := Start(ctx, "prepare", "participant", "account")
+	fake.Advance(time.Second)
+	child.AddEvent("vote", "commit", true)
+	child.RecordError(errors.New("insufficient funds"))
+	child.End()
+	root.End()
+	root.End() // ending twice exports once
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans; want 2", len(spans))
+	}
+	prepare, transaction := spans[0], spans[1]
+	if prepare.ParentID != transaction.SpanID || prepare.TraceID != transaction.TraceID {
+		t.Errorf("prepare is not a child of transaction: %+v / %+v", prepare, transaction)
+	}
+	if prepare.Duration != time.Second {
+		t.Errorf("prepare lasted %v; want 1s", prepare.Duration)
+	}
+	if prepare.Error != "insufficient funds" || len(prepare.Events) != 1 || prepare.Attributes["participant"] != "account" {
+		t.Errorf("prepare = %+v; want its error, event and attributes", prepare)
+	}
+}
+
+func TestStartWithoutTracerIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "untraced")
+	span.SetAttributes("k", "v")
+	span.AddEvent("e")
+	span.End()
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Error("Start without a tracer should return a nil span")
+	}
+}
+
+func TestEndedSpanDataDoesNotChange(t *testing.T) {
+	exp := &InMemoryExporter{}
+	_, span := Start(WithTracer(context.Background(), NewTracer(exp)), "op", "k", "before")
+	span.End()
+	span.SetAttributes("k", "after")
+
+	if got := exp.Spans()[0].Attributes["k"]; got != "before" {
+		t.Errorf("exported attribute k = %v after SetAttributes on the ended span; want before", got)
+	}
+}
+
+func TestJSONFileExporterWritesOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exp, err := NewJSONFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Start(WithTracer(context.Background(), NewTracer(exp)), "op")
+	span.End()
+	if err := exp.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got SpanData
+	if err := json.Unmarshal(b, &got); err != nil || got.Name != "op" {
+		t.Errorf("trace file = %q (%v); want the op span", b, err)
+	}
+}